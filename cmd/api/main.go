@@ -11,8 +11,14 @@ import (
 	"github.com/froz42/kerbernetes/internal/controllers"
 	"github.com/froz42/kerbernetes/internal/openapi"
 	"github.com/froz42/kerbernetes/internal/services"
+	clusterssvc "github.com/froz42/kerbernetes/internal/services/clusters"
 	envsvc "github.com/froz42/kerbernetes/internal/services/env"
 	ldapgroupbindingssvc "github.com/froz42/kerbernetes/internal/services/k8s/ldapgroupbindings"
+	namespacessvc "github.com/froz42/kerbernetes/internal/services/k8s/namespaces"
+	signingkeyssvc "github.com/froz42/kerbernetes/internal/services/k8s/signingkeys"
+	keytabsvc "github.com/froz42/kerbernetes/internal/services/keytab"
+	proxysvc "github.com/froz42/kerbernetes/internal/services/proxy"
+	reconcilersvc "github.com/froz42/kerbernetes/internal/services/reconciler"
 	"github.com/go-chi/chi/v5"
 	"github.com/samber/do"
 
@@ -53,6 +59,66 @@ func apiBootstrap() {
 		}
 	}()
 
+	namespaces := do.MustInvoke[namespacessvc.NamespacesService](injector)
+
+	go func() {
+		err := namespaces.Start(context.Background())
+		if err != nil {
+			logger.Error("Failed to start Namespaces service", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	keytab := do.MustInvoke[keytabsvc.KeytabSvc](injector)
+
+	go func() {
+		err := keytab.Start(context.Background())
+		if err != nil {
+			logger.Error("Failed to start keytab service", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	signingKeys := do.MustInvoke[signingkeyssvc.SigningKeysSvc](injector)
+
+	go func() {
+		err := signingKeys.Start(context.Background())
+		if err != nil {
+			logger.Error("Failed to start signing keys service", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	proxy := do.MustInvoke[proxysvc.ProxySvc](injector)
+
+	go func() {
+		err := proxy.Start(context.Background())
+		if err != nil {
+			logger.Error("Failed to start proxy service", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	clusters := do.MustInvoke[clusterssvc.ClustersSvc](injector)
+
+	go func() {
+		err := clusters.Start(context.Background())
+		if err != nil {
+			logger.Error("Failed to start clusters service", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	reconciler := do.MustInvoke[reconcilersvc.ReconcilerService](injector)
+
+	go func() {
+		err := reconciler.Start(context.Background())
+		if err != nil {
+			logger.Error("Failed to start reconciler service", "error", err)
+			os.Exit(1)
+		}
+	}()
+
 	env := do.MustInvoke[envsvc.EnvSvc](injector).GetEnv()
 
 	router := chi.NewRouter()
@@ -65,6 +131,32 @@ func apiBootstrap() {
 
 	router.Route(env.APIPrefix, apiMux(injector))
 
+	// the API server only calls admission and token webhooks over TLS, they are kept off
+	// the public router
+	if env.WebhookEnabled {
+		webhookRouter := chi.NewRouter()
+		webhookRouter.Use(httplog.RequestLogger(logger, &httplog.Options{
+			Level:         slog.LevelInfo,
+			Schema:        httplog.SchemaECS,
+			RecoverPanics: true,
+		}))
+		webhookRouter.Route(env.APIPrefix, webhookMux(injector))
+
+		go func() {
+			logger.Info("Started webhook server", "port", env.WebhookPort)
+			err := http.ListenAndServeTLS(
+				fmt.Sprintf(":%d", env.WebhookPort),
+				env.WebhookCertPath,
+				env.WebhookKeyPath,
+				webhookRouter,
+			)
+			if err != nil {
+				logger.Error("Failed to start webhook server", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	logger.Info("Started API server", "port", env.HTTPPort, "prefix", env.APIPrefix)
 	err = http.ListenAndServe(fmt.Sprintf(":%d", env.HTTPPort), router)
 	if err != nil {
@@ -93,6 +185,27 @@ func apiMux(
 	}
 }
 
+// webhookMux returns a function that initializes the webhook routes
+func webhookMux(
+	injector *do.Injector,
+) func(chi.Router) {
+	logger := do.MustInvoke[*slog.Logger](injector)
+	return func(router chi.Router) {
+		humaConfig := huma.DefaultConfig("Kerbernetes webhooks", "dev")
+		// only the API server calls these routes, they need no docs nor schema links
+		humaConfig.OpenAPIPath = ""
+		humaConfig.DocsPath = ""
+		humaConfig.SchemasPath = ""
+		humaConfig.CreateHooks = nil
+		api := humachi.New(router, humaConfig)
+		err := controllers.WebhookControllersInit(api, injector)
+		if err != nil {
+			logger.Error("Failed to initialize webhook controllers", "error", err)
+			os.Exit(1)
+		}
+	}
+}
+
 func notFoundHandler(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "404 page not found", http.StatusNotFound)
 }
@@ -2,27 +2,43 @@ package authctrl
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/url"
+	"strings"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/froz42/kerbernetes/internal/middlewares"
 	"github.com/froz42/kerbernetes/internal/security"
 	authsvc "github.com/froz42/kerbernetes/internal/services/auth"
+	clusterssvc "github.com/froz42/kerbernetes/internal/services/clusters"
 	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	k8smodels "github.com/froz42/kerbernetes/internal/services/k8s/models"
+	keytabsvc "github.com/froz42/kerbernetes/internal/services/keytab"
+	kubeconfigsvc "github.com/froz42/kerbernetes/internal/services/kubeconfig"
 	"github.com/samber/do"
+	"sigs.k8s.io/yaml"
 )
 
 type authController struct {
-	authSvc authsvc.AuthService
-	env     envsvc.Env
-	logger  *slog.Logger
+	authSvc       authsvc.AuthService
+	keytabSvc     keytabsvc.KeytabSvc
+	kubeconfigSvc kubeconfigsvc.KubeconfigSvc
+	clustersSvc   clusterssvc.ClustersSvc
+	env           envsvc.Env
+	logger        *slog.Logger
 }
 
 func Init(api huma.API, injector *do.Injector) {
 	authController := &authController{
-		authSvc: do.MustInvoke[authsvc.AuthService](injector),
-		env:     do.MustInvoke[envsvc.EnvSvc](injector).GetEnv(),
-		logger:  do.MustInvoke[*slog.Logger](injector),
+		authSvc:       do.MustInvoke[authsvc.AuthService](injector),
+		keytabSvc:     do.MustInvoke[keytabsvc.KeytabSvc](injector),
+		kubeconfigSvc: do.MustInvoke[kubeconfigsvc.KubeconfigSvc](injector),
+		clustersSvc:   do.MustInvoke[clusterssvc.ClustersSvc](injector),
+		env:           do.MustInvoke[envsvc.EnvSvc](injector).GetEnv(),
+		logger:        do.MustInvoke[*slog.Logger](injector),
 	}
 	authController.Register(api)
 }
@@ -35,19 +51,180 @@ func (ctrl *authController) Register(api huma.API) {
 		Description: `This endpoint is used to handle the Kerberos authentication.`,
 		Tags:        []string{"Authentification"},
 		OperationID: "getKerberosAuth",
-		Middlewares: huma.Middlewares{middlewares.SPNEGO(ctrl.logger, ctrl.env.KeytabPath)},
+		Middlewares: huma.Middlewares{middlewares.SPNEGO(
+			ctrl.logger,
+			ctrl.keytabSvc.Keytab,
+			ctrl.env.PACEnabled,
+		)},
 	}, ctrl.getKerberosAuth)
+
+	huma.Register(api, huma.Operation{
+		Method:  "POST",
+		Path:    "/auth/kerberos",
+		Summary: "Kerberos auth with exec info",
+		Description: `This endpoint is used to handle the Kerberos authentication, the ` +
+			`ExecCredential API version is negotiated from the KUBERNETES_EXEC_INFO ` +
+			`content sent by the credential plugin.`,
+		Tags:        []string{"Authentification"},
+		OperationID: "postKerberosAuth",
+		Middlewares: huma.Middlewares{middlewares.SPNEGO(
+			ctrl.logger,
+			ctrl.keytabSvc.Keytab,
+			ctrl.env.PACEnabled,
+		)},
+	}, ctrl.postKerberosAuth)
+
+	huma.Register(api, huma.Operation{
+		Method:  "GET",
+		Path:    "/auth/kubeconfig",
+		Summary: "Kubeconfig",
+		Description: `This endpoint returns a kubeconfig using the Kerberos credential plugin ` +
+			`against this instance.`,
+		Tags:        []string{"Authentification"},
+		OperationID: "getKubeconfig",
+		Middlewares: huma.Middlewares{middlewares.SPNEGO(
+			ctrl.logger,
+			ctrl.keytabSvc.Keytab,
+			ctrl.env.PACEnabled,
+		)},
+	}, ctrl.getKubeconfig)
+
+	huma.Register(api, huma.Operation{
+		Method:  "GET",
+		Path:    "/auth/kerberos/{cluster}",
+		Summary: "Kerberos auth for a cluster",
+		Description: `This endpoint is used to handle the Kerberos authentication for a ` +
+			`cluster registered with a KerbernetesCluster.`,
+		Tags:        []string{"Authentification"},
+		OperationID: "getClusterKerberosAuth",
+		Middlewares: huma.Middlewares{middlewares.SPNEGO(
+			ctrl.logger,
+			ctrl.keytabSvc.Keytab,
+			ctrl.env.PACEnabled,
+		)},
+	}, ctrl.getClusterKerberosAuth)
+
+	huma.Register(api, huma.Operation{
+		Method:  "POST",
+		Path:    "/auth/kerberos/{cluster}",
+		Summary: "Kerberos auth for a cluster with exec info",
+		Description: `This endpoint is used to handle the Kerberos authentication for a ` +
+			`cluster registered with a KerbernetesCluster, the ExecCredential API version ` +
+			`is negotiated from the KUBERNETES_EXEC_INFO content sent by the credential plugin.`,
+		Tags:        []string{"Authentification"},
+		OperationID: "postClusterKerberosAuth",
+		Middlewares: huma.Middlewares{middlewares.SPNEGO(
+			ctrl.logger,
+			ctrl.keytabSvc.Keytab,
+			ctrl.env.PACEnabled,
+		)},
+	}, ctrl.postClusterKerberosAuth)
+
+	huma.Register(api, huma.Operation{
+		Method:  "GET",
+		Path:    "/auth/kubeconfig/{cluster}",
+		Summary: "Kubeconfig for a cluster",
+		Description: `This endpoint returns a kubeconfig using the Kerberos credential plugin ` +
+			`against this instance for a cluster registered with a KerbernetesCluster.`,
+		Tags:        []string{"Authentification"},
+		OperationID: "getClusterKubeconfig",
+		Middlewares: huma.Middlewares{middlewares.SPNEGO(
+			ctrl.logger,
+			ctrl.keytabSvc.Keytab,
+			ctrl.env.PACEnabled,
+		)},
+	}, ctrl.getClusterKubeconfig)
 }
 
 func (ctrl *authController) getKerberosAuth(
 	ctx context.Context,
-	input *struct{},
+	input *kerberosAuthInput,
+) (*kerberosAuthOutput, error) {
+	return ctrl.kerberosAuth(ctx, ctrl.authSvc, input.APIVersion)
+}
+
+func (ctrl *authController) postKerberosAuth(
+	ctx context.Context,
+	input *kerberosAuthExecInfoInput,
+) (*kerberosAuthOutput, error) {
+	apiVersion, err := execInfoAPIVersion(input.APIVersion, input.Body)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.kerberosAuth(ctx, ctrl.authSvc, apiVersion)
+}
+
+func (ctrl *authController) getClusterKerberosAuth(
+	ctx context.Context,
+	input *clusterKerberosAuthInput,
+) (*kerberosAuthOutput, error) {
+	cluster, err := ctrl.cluster(input.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.kerberosAuth(ctx, cluster.Auth, input.APIVersion)
+}
+
+func (ctrl *authController) postClusterKerberosAuth(
+	ctx context.Context,
+	input *clusterKerberosAuthExecInfoInput,
+) (*kerberosAuthOutput, error) {
+	cluster, err := ctrl.cluster(input.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	apiVersion, err := execInfoAPIVersion(input.APIVersion, input.Body)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.kerberosAuth(ctx, cluster.Auth, apiVersion)
+}
+
+// cluster returns the services of a registered cluster
+func (ctrl *authController) cluster(name string) (*clusterssvc.Cluster, error) {
+	cluster, err := ctrl.clustersSvc.Get(name)
+	if errors.Is(err, clusterssvc.ErrClusterNotFound) {
+		return nil, huma.Error404NotFound(
+			fmt.Sprintf("cluster %q is not registered or not ready", name),
+		)
+	}
+	return cluster, err
+}
+
+// execInfoAPIVersion returns the ExecCredential API version requested by the query
+// parameter or the KUBERNETES_EXEC_INFO content, which must agree.
+func execInfoAPIVersion(
+	apiVersion string,
+	execInfo *k8smodels.ExecCredentialRequest,
+) (string, error) {
+	if execInfo == nil {
+		return apiVersion, nil
+	}
+	if apiVersion != "" && apiVersion != execInfo.ApiVersion {
+		return "", huma.Error400BadRequest(fmt.Sprintf(
+			"apiVersion query parameter %q does not match the ExecCredential apiVersion %q",
+			apiVersion,
+			execInfo.ApiVersion,
+		))
+	}
+	return execInfo.ApiVersion, nil
+}
+
+// kerberosAuth issues credentials for the authenticated principal, as v1beta1 when
+// apiVersion is empty for clients predating the negotiation.
+func (ctrl *authController) kerberosAuth(
+	ctx context.Context,
+	authSvc authsvc.AuthService,
+	apiVersion string,
 ) (*kerberosAuthOutput, error) {
 	principal, err := security.GetPrincipalFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	creds, err := ctrl.authSvc.AuthAccount(ctx, principal)
+	if apiVersion == "" {
+		apiVersion = k8smodels.ExecCredentialV1Beta1
+	}
+	creds, err := authSvc.AuthAccount(ctx, principal, apiVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -55,3 +232,66 @@ func (ctrl *authController) getKerberosAuth(
 		Body: creds,
 	}, nil
 }
+
+func (ctrl *authController) getKubeconfig(
+	ctx context.Context,
+	input *kubeconfigInput,
+) (*kubeconfigOutput, error) {
+	return ctrl.kubeconfig(ctx, ctrl.kubeconfigSvc, input.origin, "/auth/kerberos", input.Format)
+}
+
+func (ctrl *authController) getClusterKubeconfig(
+	ctx context.Context,
+	input *clusterKubeconfigInput,
+) (*kubeconfigOutput, error) {
+	cluster, err := ctrl.cluster(input.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.kubeconfig(
+		ctx,
+		cluster.Kubeconfig,
+		input.origin,
+		"/auth/kerberos/"+url.PathEscape(cluster.Name),
+		input.Format,
+	)
+}
+
+// kubeconfig generates the kubeconfig of the authenticated principal whose credential
+// plugin calls the auth endpoint at authPath.
+func (ctrl *authController) kubeconfig(
+	ctx context.Context,
+	kubeconfigSvc kubeconfigsvc.KubeconfigSvc,
+	origin requestOrigin,
+	authPath string,
+	format string,
+) (*kubeconfigOutput, error) {
+	principal, err := security.GetPrincipalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := ctrl.env.PublicURL
+	if baseURL == "" {
+		baseURL = origin.baseURL(ctrl.env)
+	}
+	authURL := strings.TrimSuffix(baseURL, "/") + ctrl.env.APIPrefix + authPath
+
+	kubeconfig, err := kubeconfigSvc.Generate(ctx, principal, authURL)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &kubeconfigOutput{ContentType: "application/yaml"}
+	if format == "json" {
+		output.ContentType = "application/json"
+		output.Body, err = json.MarshalIndent(kubeconfig, "", "  ")
+	} else {
+		output.Body, err = yaml.Marshal(kubeconfig)
+	}
+	if err != nil {
+		ctrl.logger.Error("Failed to encode kubeconfig", "principal", principal, "error", err)
+		return nil, huma.Error500InternalServerError("Failed to encode kubeconfig")
+	}
+	return output, nil
+}
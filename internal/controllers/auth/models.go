@@ -1,7 +1,100 @@
 package authctrl
 
-import k8smodels "github.com/froz42/kerbernetes/internal/services/k8s/models"
+import (
+	"github.com/danielgtaylor/huma/v2"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	k8smodels "github.com/froz42/kerbernetes/internal/services/k8s/models"
+)
+
+type kerberosAuthInput struct {
+	APIVersion string `query:"apiVersion" enum:"client.authentication.k8s.io/v1,client.authentication.k8s.io/v1beta1" doc:"API version of the returned ExecCredential, v1beta1 when unset"`
+}
+
+type kerberosAuthExecInfoInput struct {
+	APIVersion string                           `query:"apiVersion" enum:"client.authentication.k8s.io/v1,client.authentication.k8s.io/v1beta1" doc:"API version of the returned ExecCredential, v1beta1 when unset"`
+	Body       *k8smodels.ExecCredentialRequest `required:"false" doc:"Content of KUBERNETES_EXEC_INFO"`
+}
+
+type clusterKerberosAuthInput struct {
+	Cluster    string `path:"cluster" doc:"Name of the KerbernetesCluster to issue credentials for"`
+	APIVersion string `query:"apiVersion" enum:"client.authentication.k8s.io/v1,client.authentication.k8s.io/v1beta1" doc:"API version of the returned ExecCredential, v1beta1 when unset"`
+}
+
+type clusterKerberosAuthExecInfoInput struct {
+	Cluster    string                           `path:"cluster" doc:"Name of the KerbernetesCluster to issue credentials for"`
+	APIVersion string                           `query:"apiVersion" enum:"client.authentication.k8s.io/v1,client.authentication.k8s.io/v1beta1" doc:"API version of the returned ExecCredential, v1beta1 when unset"`
+	Body       *k8smodels.ExecCredentialRequest `required:"false" doc:"Content of KUBERNETES_EXEC_INFO"`
+}
 
 type kerberosAuthOutput struct {
 	Body *k8smodels.Credentials
 }
+
+type kubeconfigInput struct {
+	Format string `query:"format" enum:"yaml,json" default:"yaml" doc:"Output format of the kubeconfig"`
+
+	origin requestOrigin
+}
+
+// Resolve records how the request reached this instance.
+func (i *kubeconfigInput) Resolve(ctx huma.Context) []error {
+	i.origin = newRequestOrigin(ctx)
+	return nil
+}
+
+type clusterKubeconfigInput struct {
+	Cluster string `path:"cluster" doc:"Name of the KerbernetesCluster to generate a kubeconfig for"`
+	Format  string `query:"format" enum:"yaml,json" default:"yaml" doc:"Output format of the kubeconfig"`
+
+	origin requestOrigin
+}
+
+// Resolve records how the request reached this instance.
+func (i *clusterKubeconfigInput) Resolve(ctx huma.Context) []error {
+	i.origin = newRequestOrigin(ctx)
+	return nil
+}
+
+// requestOrigin is how a request reached this instance
+type requestOrigin struct {
+	scheme     string
+	host       string
+	remoteAddr string
+	// forwardedProto and forwardedHost are the headers set by reverse proxies
+	forwardedProto string
+	forwardedHost  string
+}
+
+func newRequestOrigin(ctx huma.Context) requestOrigin {
+	origin := requestOrigin{
+		scheme:         "http",
+		host:           ctx.Host(),
+		remoteAddr:     ctx.RemoteAddr(),
+		forwardedProto: ctx.Header("X-Forwarded-Proto"),
+		forwardedHost:  ctx.Header("X-Forwarded-Host"),
+	}
+	if ctx.TLS() != nil {
+		origin.scheme = "https"
+	}
+	return origin
+}
+
+// baseURL returns the scheme and host the request reached this instance with, honoring
+// the headers set by reverse proxies when the request comes from a trusted one
+func (o requestOrigin) baseURL(env envsvc.Env) string {
+	scheme, host := o.scheme, o.host
+	if env.IsTrustedProxy(o.remoteAddr) {
+		if o.forwardedProto != "" {
+			scheme = o.forwardedProto
+		}
+		if o.forwardedHost != "" {
+			host = o.forwardedHost
+		}
+	}
+	return scheme + "://" + host
+}
+
+type kubeconfigOutput struct {
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
@@ -3,6 +3,9 @@ package controllers
 import (
 	"github.com/danielgtaylor/huma/v2"
 	authcontroller "github.com/froz42/kerbernetes/internal/controllers/auth"
+	ldapcontroller "github.com/froz42/kerbernetes/internal/controllers/ldap"
+	oidccontroller "github.com/froz42/kerbernetes/internal/controllers/oidc"
+	webhookscontroller "github.com/froz42/kerbernetes/internal/controllers/webhooks"
 	"github.com/samber/do"
 )
 
@@ -13,6 +16,16 @@ type controllerInitFunc func(api huma.API, injector *do.Injector)
 func controllersList() []controllerInitFunc {
 	return []controllerInitFunc{
 		authcontroller.Init,
+		ldapcontroller.Init,
+		oidccontroller.Init,
+	}
+}
+
+// webhookControllersList returns the init functions of the controllers called by the
+// Kubernetes API server, served on the webhook TLS port only
+func webhookControllersList() []controllerInitFunc {
+	return []controllerInitFunc{
+		webhookscontroller.Init,
 	}
 }
 
@@ -25,3 +38,12 @@ func ControllersInit(api huma.API, injector *do.Injector) error {
 
 	return nil
 }
+
+// WebhookControllersInit initializes the webhook controllers
+func WebhookControllersInit(api huma.API, injector *do.Injector) error {
+	for _, controller := range webhookControllersList() {
+		controller(api, injector)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package ldapctrl
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/froz42/kerbernetes/internal/middlewares"
+	"github.com/froz42/kerbernetes/internal/security"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	groupssvc "github.com/froz42/kerbernetes/internal/services/groups"
+	keytabsvc "github.com/froz42/kerbernetes/internal/services/keytab"
+	ldapsvc "github.com/froz42/kerbernetes/internal/services/ldap"
+	"github.com/samber/do"
+)
+
+type ldapController struct {
+	ldapSvc   ldapsvc.LDAPSvc
+	groupsSvc groupssvc.GroupsSvc
+	keytabSvc keytabsvc.KeytabSvc
+	env       envsvc.Env
+	logger    *slog.Logger
+}
+
+func Init(api huma.API, injector *do.Injector) {
+	ldapController := &ldapController{
+		ldapSvc:   do.MustInvoke[ldapsvc.LDAPSvc](injector),
+		groupsSvc: do.MustInvoke[groupssvc.GroupsSvc](injector),
+		keytabSvc: do.MustInvoke[keytabsvc.KeytabSvc](injector),
+		env:       do.MustInvoke[envsvc.EnvSvc](injector).GetEnv(),
+		logger:    do.MustInvoke[*slog.Logger](injector).With("controller", "ldap"),
+	}
+	ldapController.Register(api)
+}
+
+func (ctrl *ldapController) Register(api huma.API) {
+	huma.Register(api, huma.Operation{
+		Method:  "GET",
+		Path:    "/ldap/pool",
+		Summary: "LDAP connection pool statistics",
+		Description: `This endpoint returns the statistics of the LDAP connection pool, ` +
+			`used to size it. Realms overriding the LDAP settings have their own pool. It is ` +
+			`restricted to the admin principals.`,
+		Tags:        []string{"LDAP"},
+		OperationID: "getLDAPPoolStats",
+		Middlewares: huma.Middlewares{ctrl.spnego()},
+	}, ctrl.getPoolStats)
+
+	huma.Register(api, huma.Operation{
+		Method:  "GET",
+		Path:    "/ldap/servers",
+		Summary: "LDAP servers health",
+		Description: `This endpoint returns the LDAP servers in configured or discovered ` +
+			`order. Failing servers are quarantined, and only tried once every other server ` +
+			`failed. It is restricted to the admin principals.`,
+		Tags:        []string{"LDAP"},
+		OperationID: "getLDAPServers",
+		Middlewares: huma.Middlewares{ctrl.spnego()},
+	}, ctrl.getServers)
+
+	huma.Register(api, huma.Operation{
+		Method:  "DELETE",
+		Path:    "/ldap/cache/users/{principal}",
+		Summary: "Purge a cached user",
+		Description: `This endpoint drops the cached LDAP entry and groups of a principal, ` +
+			`so that its next authentication reads the directory. It is restricted to the ` +
+			`admin principals.`,
+		Tags:        []string{"LDAP"},
+		OperationID: "purgeLDAPCachedUser",
+		Middlewares: huma.Middlewares{ctrl.spnego()},
+	}, ctrl.purgeUser)
+}
+
+// spnego returns the middleware authenticating the admin endpoints
+func (ctrl *ldapController) spnego() func(huma.Context, func(huma.Context)) {
+	return middlewares.SPNEGO(ctrl.logger, ctrl.keytabSvc.Keytab, ctrl.env.PACEnabled)
+}
+
+// requireAdmin returns the authenticated principal, or an error when it is not an admin
+func (ctrl *ldapController) requireAdmin(ctx context.Context) (string, error) {
+	admin, err := security.GetPrincipalFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !ctrl.env.IsAdmin(admin) {
+		return "", huma.Error403Forbidden("admin principal required")
+	}
+	return admin, nil
+}
+
+func (ctrl *ldapController) getPoolStats(
+	ctx context.Context,
+	input *struct{},
+) (*poolStatsOutput, error) {
+	if _, err := ctrl.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if !ctrl.env.LDAPEnabled {
+		return nil, huma.Error404NotFound("LDAP is not enabled")
+	}
+
+	stats := ctrl.ldapSvc.PoolStats()
+	return &poolStatsOutput{
+		Body: &poolStats{
+			MaxSize:     stats.MaxSize,
+			Open:        stats.Open,
+			Idle:        stats.Idle,
+			InUse:       stats.InUse,
+			Dials:       stats.Dials,
+			Reuses:      stats.Reuses,
+			Discarded:   stats.Discarded,
+			Expired:     stats.Expired,
+			Waits:       stats.Waits,
+			WaitSeconds: stats.WaitDuration.Seconds(),
+		},
+	}, nil
+}
+
+func (ctrl *ldapController) getServers(
+	ctx context.Context,
+	input *struct{},
+) (*serversOutput, error) {
+	if _, err := ctrl.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if !ctrl.env.LDAPEnabled {
+		return nil, huma.Error404NotFound("LDAP is not enabled")
+	}
+
+	servers := ctrl.ldapSvc.Servers()
+	output := &serversOutput{Body: make([]serverStatus, 0, len(servers))}
+	for _, server := range servers {
+		status := serverStatus{URL: server.URL, Failures: server.Failures}
+		if !server.QuarantinedUntil.IsZero() {
+			status.QuarantinedUntil = &server.QuarantinedUntil
+		}
+		output.Body = append(output.Body, status)
+	}
+	return output, nil
+}
+
+func (ctrl *ldapController) purgeUser(
+	ctx context.Context,
+	input *purgeUserInput,
+) (*purgeUserOutput, error) {
+	admin, err := ctrl.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ctrl.env.LDAPEnabled {
+		return nil, huma.Error404NotFound("LDAP is not enabled")
+	}
+
+	purged := ctrl.groupsSvc.PurgeUser(input.Principal)
+	ctrl.logger.Info("Purge of cached user requested",
+		"admin", admin,
+		"principal", input.Principal,
+		"purged", purged,
+	)
+	return &purgeUserOutput{Body: &purgeUserResult{Purged: purged}}, nil
+}
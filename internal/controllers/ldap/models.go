@@ -0,0 +1,44 @@
+package ldapctrl
+
+import "time"
+
+// poolStats describes the LDAP connections, to size the pool
+type poolStats struct {
+	MaxSize     int     `json:"maxSize" description:"Maximum number of open connections"`
+	Open        int     `json:"open" description:"Open connections, idle or in use"`
+	Idle        int     `json:"idle" description:"Connections waiting to be reused"`
+	InUse       int     `json:"inUse" description:"Connections running an operation"`
+	Dials       uint64  `json:"dials" description:"Connections opened and bound"`
+	Reuses      uint64  `json:"reuses" description:"Operations run on an idle connection"`
+	Discarded   uint64  `json:"discarded" description:"Connections closed after a failed check or a network error"`
+	Expired     uint64  `json:"expired" description:"Idle connections closed after the idle timeout"`
+	Waits       uint64  `json:"waits" description:"Operations that waited for a connection, the pool being full"`
+	WaitSeconds float64 `json:"waitSeconds" description:"Total time spent waiting for a connection"`
+}
+
+type poolStatsOutput struct {
+	Body *poolStats
+}
+
+// serverStatus describes the health of an LDAP server
+type serverStatus struct {
+	URL              string     `json:"url"`
+	Failures         int        `json:"failures" description:"Consecutive failures"`
+	QuarantinedUntil *time.Time `json:"quarantinedUntil,omitempty" description:"When a failing server is tried first again"`
+}
+
+type serversOutput struct {
+	Body []serverStatus
+}
+
+type purgeUserInput struct {
+	Principal string `path:"principal" doc:"Principal of the user, in the user@REALM form when the realm is known"`
+}
+
+type purgeUserResult struct {
+	Purged bool `json:"purged" description:"Whether the user or its groups were cached"`
+}
+
+type purgeUserOutput struct {
+	Body *purgeUserResult
+}
@@ -0,0 +1,22 @@
+package oidcctrl
+
+// discovery is the subset of the OpenID provider metadata verifiers of ID tokens rely on
+type discovery struct {
+	Issuer                           string   `json:"issuer" description:"Issuer URL, the iss claim of the ID tokens"`
+	JWKSURI                          string   `json:"jwks_uri" description:"URL of the signing keys"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	ClaimsSupported                  []string `json:"claims_supported"`
+}
+
+type discoveryOutput struct {
+	CacheControl string `header:"Cache-Control"`
+	Body         *discovery
+}
+
+type jwksOutput struct {
+	ContentType  string `header:"Content-Type"`
+	CacheControl string `header:"Cache-Control"`
+	Body         []byte
+}
@@ -0,0 +1,94 @@
+package oidcctrl
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/danielgtaylor/huma/v2"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	signingkeyssvc "github.com/froz42/kerbernetes/internal/services/k8s/signingkeys"
+	"github.com/samber/do"
+)
+
+// cacheControl lets verifiers cache the documents for less than the key publish delay
+const cacheControl = "public, max-age=60"
+
+type oidcController struct {
+	signingKeysSvc signingkeyssvc.SigningKeysSvc
+	env            envsvc.Env
+	logger         *slog.Logger
+}
+
+func Init(api huma.API, injector *do.Injector) {
+	oidcController := &oidcController{
+		signingKeysSvc: do.MustInvoke[signingkeyssvc.SigningKeysSvc](injector),
+		env:            do.MustInvoke[envsvc.EnvSvc](injector).GetEnv(),
+		logger:         do.MustInvoke[*slog.Logger](injector).With("controller", "oidc"),
+	}
+	oidcController.Register(api)
+}
+
+func (ctrl *oidcController) Register(api huma.API) {
+	huma.Register(api, huma.Operation{
+		Method:  "GET",
+		Path:    "/oidc/.well-known/openid-configuration",
+		Summary: "OpenID configuration",
+		Description: `This endpoint serves the discovery document of the built-in OIDC ` +
+			`issuer, available in oidc credential mode. ID tokens are issued by the ` +
+			`Kerberos auth endpoint.`,
+		Tags:        []string{"OIDC"},
+		OperationID: "getOpenIDConfiguration",
+	}, ctrl.getOpenIDConfiguration)
+
+	huma.Register(api, huma.Operation{
+		Method:      "GET",
+		Path:        "/oidc/jwks",
+		Summary:     "JSON Web Key Set",
+		Description: `This endpoint serves the public keys verifying the ID tokens.`,
+		Tags:        []string{"OIDC"},
+		OperationID: "getJWKS",
+	}, ctrl.getJWKS)
+}
+
+func (ctrl *oidcController) getOpenIDConfiguration(
+	ctx context.Context,
+	input *struct{},
+) (*discoveryOutput, error) {
+	if ctrl.env.CredentialMode != envsvc.CredentialModeOIDC {
+		return nil, huma.Error404NotFound("OIDC issuer is not enabled")
+	}
+
+	issuer := ctrl.env.OIDCIssuerURL()
+	return &discoveryOutput{
+		CacheControl: cacheControl,
+		Body: &discovery{
+			Issuer:                           issuer,
+			JWKSURI:                          issuer + "/jwks",
+			ResponseTypesSupported:           []string{"id_token"},
+			SubjectTypesSupported:            []string{"public"},
+			IDTokenSigningAlgValuesSupported: []string{string(signingkeyssvc.Algorithm)},
+			ClaimsSupported: []string{
+				"iss", "sub", "aud", "exp", "iat", "nbf", "jti", "preferred_username", "groups",
+			},
+		},
+	}, nil
+}
+
+func (ctrl *oidcController) getJWKS(ctx context.Context, input *struct{}) (*jwksOutput, error) {
+	if ctrl.env.CredentialMode != envsvc.CredentialModeOIDC {
+		return nil, huma.Error404NotFound("OIDC issuer is not enabled")
+	}
+
+	// JSONWebKey encodes itself, the generated schema could not describe it
+	raw, err := json.Marshal(ctrl.signingKeysSvc.PublicKeys())
+	if err != nil {
+		ctrl.logger.Error("Failed to encode signing keys", "error", err)
+		return nil, huma.Error500InternalServerError("Failed to encode signing keys")
+	}
+	return &jwksOutput{
+		ContentType:  "application/jwk-set+json",
+		CacheControl: cacheControl,
+		Body:         raw,
+	}, nil
+}
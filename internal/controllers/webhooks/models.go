@@ -0,0 +1,22 @@
+package webhooksctrl
+
+import (
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+type admissionReviewInput struct {
+	Body *admissionv1.AdmissionReview
+}
+
+type admissionReviewOutput struct {
+	Body *admissionv1.AdmissionReview
+}
+
+type tokenReviewInput struct {
+	Body *authenticationv1.TokenReview
+}
+
+type tokenReviewOutput struct {
+	Body *authenticationv1.TokenReview
+}
@@ -0,0 +1,166 @@
+package webhooksctrl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+	ldapgroupbindingssvc "github.com/froz42/kerbernetes/internal/services/k8s/ldapgroupbindings"
+	tokenssvc "github.com/froz42/kerbernetes/internal/services/tokens"
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	"github.com/samber/do"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type webhooksController struct {
+	tokensSvc tokenssvc.TokensSvc
+	logger    *slog.Logger
+}
+
+func Init(api huma.API, injector *do.Injector) {
+	webhooksController := &webhooksController{
+		tokensSvc: do.MustInvoke[tokenssvc.TokensSvc](injector),
+		logger:    do.MustInvoke[*slog.Logger](injector).With("controller", "webhooks"),
+	}
+	webhooksController.Register(api)
+}
+
+func (ctrl *webhooksController) Register(api huma.API) {
+	huma.Register(api, huma.Operation{
+		Method:  "POST",
+		Path:    "/webhooks/ldapgroupbindings/validate",
+		Summary: "Validate LdapGroupBinding",
+		Description: `This endpoint is called by the Kubernetes API server to validate ` +
+			`LdapGroupBinding objects before they are stored.`,
+		Tags:        []string{"Webhooks"},
+		OperationID: "validateLdapGroupBinding",
+		// AdmissionReview embeds raw objects that the generated schema cannot describe
+		SkipValidateBody: true,
+	}, ctrl.validateLdapGroupBinding)
+
+	huma.Register(api, huma.Operation{
+		Method:  "POST",
+		Path:    "/webhooks/tokenreview",
+		Summary: "Review token",
+		Description: `This endpoint is called by the Kubernetes API server to authenticate ` +
+			`the tokens issued in webhook credential mode. It answers with the principal as ` +
+			`username and its groups.`,
+		Tags:        []string{"Webhooks"},
+		OperationID: "reviewToken",
+		// TokenReview carries Kubernetes object metadata the generated schema cannot describe
+		SkipValidateBody: true,
+	}, ctrl.reviewToken)
+}
+
+// specChanged reports whether an admission request creates a binding or changes its spec
+func specChanged(
+	request *admissionv1.AdmissionRequest,
+	binding *v1.LdapGroupBinding,
+) (bool, error) {
+	if request.Operation != admissionv1.Update {
+		return true, nil
+	}
+	old := &v1.LdapGroupBinding{}
+	if err := json.Unmarshal(request.OldObject.Raw, old); err != nil {
+		return false, err
+	}
+	return !equality.Semantic.DeepEqual(old.Spec, binding.Spec), nil
+}
+
+func (ctrl *webhooksController) validateLdapGroupBinding(
+	ctx context.Context,
+	input *admissionReviewInput,
+) (*admissionReviewOutput, error) {
+	request := input.Body.Request
+	if request == nil {
+		return nil, huma.Error400BadRequest("admission review has no request")
+	}
+
+	response := &admissionv1.AdmissionResponse{
+		UID:     request.UID,
+		Allowed: true,
+	}
+
+	// deletions carry no object to validate
+	if request.Operation != admissionv1.Delete {
+		binding := &v1.LdapGroupBinding{}
+		err := json.Unmarshal(request.Object.Raw, binding)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to decode LdapGroupBinding", err)
+		}
+		specChanged, err := specChanged(request, binding)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to decode the old LdapGroupBinding", err)
+		}
+
+		// bindings created before the validation must stay updatable by the finalizer
+		// handling, and deletable
+		var errs field.ErrorList
+		if specChanged && binding.DeletionTimestamp == nil {
+			errs = ldapgroupbindingssvc.Validate(binding)
+		}
+		if len(errs) > 0 {
+			ctrl.logger.Info(
+				"Rejected invalid LdapGroupBinding",
+				"name", binding.Name,
+				"errors", errs.ToAggregate().Error(),
+			)
+			response.Allowed = false
+			response.Result = &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusUnprocessableEntity,
+				Reason:  metav1.StatusReasonInvalid,
+				Message: errs.ToAggregate().Error(),
+			}
+		}
+	}
+
+	return &admissionReviewOutput{
+		Body: &admissionv1.AdmissionReview{
+			TypeMeta: input.Body.TypeMeta,
+			Response: response,
+		},
+	}, nil
+}
+
+func (ctrl *webhooksController) reviewToken(
+	ctx context.Context,
+	input *tokenReviewInput,
+) (*tokenReviewOutput, error) {
+	if input.Body == nil || input.Body.Spec.Token == "" {
+		return nil, huma.Error400BadRequest("token review has no token")
+	}
+
+	status := authenticationv1.TokenReviewStatus{}
+	identity, err := ctrl.tokensSvc.Review(ctx, input.Body.Spec.Token, input.Body.Spec.Audiences)
+	switch {
+	case errors.Is(err, tokenssvc.ErrInvalidToken), errors.Is(err, tokenssvc.ErrAudienceMismatch):
+		// tokens of other authenticators are expected here, this is not an error
+		ctrl.logger.Debug("Rejected token", "error", err)
+		status.Error = err.Error()
+	case err != nil:
+		ctrl.logger.Error("Failed to review token", "error", err)
+		status.Error = "failed to review token"
+	default:
+		status.Authenticated = true
+		status.User = authenticationv1.UserInfo{
+			Username: identity.Username,
+			Groups:   identity.Groups,
+		}
+		status.Audiences = identity.Audiences
+	}
+
+	return &tokenReviewOutput{
+		Body: &authenticationv1.TokenReview{
+			TypeMeta: input.Body.TypeMeta,
+			Status:   status,
+		},
+	}, nil
+}
@@ -0,0 +1,137 @@
+package webhooksctrl
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	ldapgroupbindingssvc "github.com/froz42/kerbernetes/internal/services/k8s/ldapgroupbindings"
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestValidateLdapGroupBinding(t *testing.T) {
+	valid := v1.LdapGroupBindingSpec{
+		LdapGroupDN: "cn=developers,ou=groups,dc=example,dc=com",
+		Bindings: []v1.LdapGroupBindingItem{
+			{Kind: "ClusterRole", Name: "view", ApiGroup: rbacv1.GroupName},
+		},
+	}
+	// a spec accepted before the validation existed
+	invalid := v1.LdapGroupBindingSpec{
+		LdapGroupDN: "developers",
+		Bindings: []v1.LdapGroupBindingItem{
+			{Kind: "Role", Name: "deployer", ApiGroup: rbacv1.GroupName},
+		},
+	}
+	now := metav1.Now()
+
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		old       *v1.LdapGroupBinding
+		object    *v1.LdapGroupBinding
+		want      bool
+	}{
+		{
+			name:      "valid creation",
+			operation: admissionv1.Create,
+			object:    binding(valid, nil, nil),
+			want:      true,
+		},
+		{
+			name:      "invalid creation",
+			operation: admissionv1.Create,
+			object:    binding(invalid, nil, nil),
+			want:      false,
+		},
+		{
+			name:      "update to an invalid spec",
+			operation: admissionv1.Update,
+			old:       binding(valid, nil, nil),
+			object:    binding(invalid, nil, nil),
+			want:      false,
+		},
+		{
+			name:      "finalizer added to an invalid binding",
+			operation: admissionv1.Update,
+			old:       binding(invalid, nil, nil),
+			object:    binding(invalid, []string{ldapgroupbindingssvc.Finalizer}, nil),
+			want:      true,
+		},
+		{
+			name:      "finalizer removed from a deleted invalid binding",
+			operation: admissionv1.Update,
+			old:       binding(invalid, []string{ldapgroupbindingssvc.Finalizer}, &now),
+			object:    binding(invalid, nil, &now),
+			want:      true,
+		},
+		{
+			name:      "spec changed on a deleted binding",
+			operation: admissionv1.Update,
+			old:       binding(valid, []string{ldapgroupbindingssvc.Finalizer}, &now),
+			object:    binding(invalid, []string{ldapgroupbindingssvc.Finalizer}, &now),
+			want:      true,
+		},
+		{
+			name:      "deletion",
+			operation: admissionv1.Delete,
+			old:       binding(invalid, nil, nil),
+			want:      true,
+		},
+	}
+
+	ctrl := &webhooksController{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &admissionReviewInput{Body: &admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "uid",
+					Operation: tt.operation,
+					Object:    raw(t, tt.object),
+					OldObject: raw(t, tt.old),
+				},
+			}}
+
+			output, err := ctrl.validateLdapGroupBinding(context.Background(), input)
+			if err != nil {
+				t.Fatalf("validateLdapGroupBinding: %v", err)
+			}
+			if got := output.Body.Response.Allowed; got != tt.want {
+				t.Errorf("allowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func binding(
+	spec v1.LdapGroupBindingSpec,
+	finalizers []string,
+	deletionTimestamp *metav1.Time,
+) *v1.LdapGroupBinding {
+	return &v1.LdapGroupBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "developers",
+			Finalizers:        finalizers,
+			DeletionTimestamp: deletionTimestamp,
+		},
+		Spec: spec,
+	}
+}
+
+func raw(t *testing.T, object *v1.LdapGroupBinding) runtime.RawExtension {
+	t.Helper()
+	if object == nil {
+		return runtime.RawExtension{}
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		t.Fatalf("failed to encode LdapGroupBinding: %v", err)
+	}
+	return runtime.RawExtension{Raw: data}
+}
@@ -1,16 +1,20 @@
 package middlewares
 
 import (
+	"context"
+	"fmt"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humachi"
 	"github.com/froz42/kerbernetes/internal/security"
 	"github.com/jcmturner/goidentity/v6"
+	"github.com/jcmturner/gokrb5/v8/credentials"
 	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/jcmturner/gokrb5/v8/service"
 	"github.com/jcmturner/gokrb5/v8/spnego"
 	"log"
 	"log/slog"
 	"net/http"
+	"slices"
 )
 
 type slogWriter struct {
@@ -22,35 +26,115 @@ func (w slogWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// SPNEGO authenticates requests with the keytab returned by getKeytab, which is called on
+// every request so that the keytab can be reloaded.
 func SPNEGO(
 	logger *slog.Logger,
-	keytabPath string,
+	getKeytab func() *keytab.Keytab,
+	decodePAC bool,
 ) HumaMiddleware {
-	kt, err := keytab.Load(keytabPath)
-	if err != nil {
-		log.Fatalf("Failed to load keytab: %v", err)
+	authenticate := newAuthenticator(logger, getKeytab, decodePAC)
+	return func(ctx huma.Context, next func(huma.Context)) {
+		r, w := humachi.Unwrap(ctx)
+		authenticate(w, r, func(_ *http.Request, principal string, sids []string) {
+			ctx = huma.WithValue(ctx, security.PrincipalFromContextKey, principal)
+			if sids != nil {
+				ctx = huma.WithValue(ctx, security.GroupSIDsFromContextKey, sids)
+			}
+			next(ctx)
+		})
 	}
+}
+
+// SPNEGOHandler is the net/http counterpart of SPNEGO, the principal and group SIDs are
+// stored in the request context.
+func SPNEGOHandler(
+	logger *slog.Logger,
+	getKeytab func() *keytab.Keytab,
+	decodePAC bool,
+	next http.Handler,
+) http.Handler {
+	authenticate := newAuthenticator(logger, getKeytab, decodePAC)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticate(w, r, func(r *http.Request, principal string, sids []string) {
+			ctx := context.WithValue(r.Context(), security.PrincipalFromContextKey, principal)
+			if sids != nil {
+				ctx = context.WithValue(ctx, security.GroupSIDsFromContextKey, sids)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+}
+
+// authenticator runs the SPNEGO negotiation and calls onAuth with the authenticated
+// principal, sids is nil when the ticket carried no PAC or PAC decoding is disabled.
+type authenticator func(
+	w http.ResponseWriter,
+	r *http.Request,
+	onAuth func(r *http.Request, principal string, sids []string),
+)
+
+func newAuthenticator(
+	logger *slog.Logger,
+	getKeytab func() *keytab.Keytab,
+	decodePAC bool,
+) authenticator {
 	logger = logger.With(slog.String("middleware", "SPNEGO"))
-	logger.Info("SPNEGO middleware initialized", "keytabPath", keytabPath)
+	logger.Info(
+		"SPNEGO middleware initialized",
+		"decodePAC", decodePAC,
+	)
 	l := log.New(slogWriter{logger: logger}, "", 0)
-	return func(ctx huma.Context, next func(huma.Context)) {
-		r, w := humachi.Unwrap(ctx)
+	return func(
+		w http.ResponseWriter,
+		r *http.Request,
+		onAuth func(r *http.Request, principal string, sids []string),
+	) {
+		kt := getKeytab()
+		if kt == nil {
+			logger.Error("Rejecting request, no keytab loaded")
+			http.Error(w, "keytab not loaded", http.StatusServiceUnavailable)
+			return
+		}
 
 		inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 			creds := goidentity.FromHTTPRequestContext(r)
 			principal := creds.UserName()
+			if realm := creds.Domain(); realm != "" {
+				principal += "@" + realm
+			}
 
-			ctx = huma.WithValue(ctx, security.PrincipalFromContextKey, principal)
-			next(ctx)
+			var sids []string
+			if groupSIDs, ok := groupSIDs(creds); decodePAC && ok {
+				sids = groupSIDs
+			}
+			onAuth(r, principal, sids)
 		})
 
 		authHandler := spnego.SPNEGOKRB5Authenticate(
 			inner,
 			kt,
 			service.Logger(l),
-			service.DecodePAC(false),
+			service.DecodePAC(decodePAC),
 		)
 		authHandler.ServeHTTP(w, r)
 	}
 }
+
+// groupSIDs returns the group SIDs found in the ticket PAC, including the primary group.
+// ok is false when the ticket did not carry a PAC.
+func groupSIDs(creds goidentity.Identity) ([]string, bool) {
+	adCreds, ok := creds.Attributes()[credentials.AttributeKeyADCredentials].(credentials.ADCredentials)
+	if !ok {
+		return nil, false
+	}
+	sids := append([]string{}, adCreds.GroupMembershipSIDs...)
+	if adCreds.LogonDomainID != "" && adCreds.PrimaryGroupID != 0 {
+		primary := fmt.Sprintf("%s-%d", adCreds.LogonDomainID, adCreds.PrimaryGroupID)
+		if !slices.Contains(sids, primary) {
+			sids = append(sids, primary)
+		}
+	}
+	return sids, true
+}
@@ -0,0 +1,12 @@
+package security
+
+import "context"
+
+const GroupSIDsFromContextKey = "groupSIDs"
+
+// GetGroupSIDsFromContext returns the group SIDs decoded from the Kerberos ticket PAC.
+// ok is false when the ticket carried no PAC or PAC decoding is disabled.
+func GetGroupSIDsFromContext(ctx context.Context) (sids []string, ok bool) {
+	sids, ok = ctx.Value(GroupSIDsFromContextKey).([]string)
+	return sids, ok
+}
@@ -2,12 +2,15 @@ package security
 
 import (
 	"context"
+	"strings"
 
 	"github.com/danielgtaylor/huma/v2"
 )
 
 const PrincipalFromContextKey = "principal"
 
+// GetPrincipalFromContext returns the authenticated principal, in the user@REALM form
+// when the realm is known.
 func GetPrincipalFromContext(ctx context.Context) (string, error) {
 	principal, ok := ctx.Value(PrincipalFromContextKey).(string)
 	if !ok || principal == "" {
@@ -15,3 +18,12 @@ func GetPrincipalFromContext(ctx context.Context) (string, error) {
 	}
 	return principal, nil
 }
+
+// SplitPrincipal splits a principal into its username, instance included, and realm.
+func SplitPrincipal(principal string) (username string, realm string) {
+	i := strings.LastIndex(principal, "@")
+	if i < 0 {
+		return principal, ""
+	}
+	return principal[:i], principal[i+1:]
+}
@@ -0,0 +1,12 @@
+package security
+
+import "strings"
+
+// reservedPrefix is the prefix of the users and groups Kubernetes gives a special meaning to
+const reservedPrefix = "system:"
+
+// IsReservedIdentity reports whether a user or group name is reserved by Kubernetes, such
+// as system:masters, and must never be asserted on behalf of a principal.
+func IsReservedIdentity(name string) bool {
+	return strings.HasPrefix(name, reservedPrefix)
+}
@@ -2,42 +2,74 @@ package authsvc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/froz42/kerbernetes/internal/security"
 	envsvc "github.com/froz42/kerbernetes/internal/services/env"
-	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
-	ldapgroupbindingssvc "github.com/froz42/kerbernetes/internal/services/k8s/ldapgroupbindings"
+	groupssvc "github.com/froz42/kerbernetes/internal/services/groups"
+	certificatessvc "github.com/froz42/kerbernetes/internal/services/k8s/certificates"
 	k8smodels "github.com/froz42/kerbernetes/internal/services/k8s/models"
 	serviceaccountssvc "github.com/froz42/kerbernetes/internal/services/k8s/serviceaccounts"
-	ldapsvc "github.com/froz42/kerbernetes/internal/services/ldap"
-	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	signingkeyssvc "github.com/froz42/kerbernetes/internal/services/k8s/signingkeys"
+	pacsvc "github.com/froz42/kerbernetes/internal/services/pac"
+	realmssvc "github.com/froz42/kerbernetes/internal/services/realms"
+	reconcilersvc "github.com/froz42/kerbernetes/internal/services/reconciler"
+	tokenssvc "github.com/froz42/kerbernetes/internal/services/tokens"
 	"github.com/samber/do"
-	corev1 "k8s.io/api/core/v1"
-	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 type AuthService interface {
-	AuthAccount(ctx context.Context, username string) (*k8smodels.Credentials, error)
+	// AuthAccount issues credentials for a principal as an ExecCredential of the given API
+	// version, a service account token, a client certificate, a webhook token or an ID
+	// token depending on the credential mode
+	AuthAccount(
+		ctx context.Context,
+		principal string,
+		apiVersion string,
+	) (*k8smodels.Credentials, error)
+
+	// ReconcileAccount upserts the service account mapped from a principal and reconciles
+	// its bindings without issuing credentials
+	ReconcileAccount(ctx context.Context, principal string) (types.NamespacedName, error)
+
+	// ResolveGroups returns the groups of an allowed principal, from the ticket PAC or the
+	// group providers. ok is false when no group source is enabled.
+	ResolveGroups(ctx context.Context, principal string) (groups []string, ok bool, err error)
+
+	// CheckAccount refuses principals whose directory account is disabled, locked or
+	// expired with a 403
+	CheckAccount(principal string) error
 }
 
 type authService struct {
-	env                  envsvc.Env
-	k8sSvc               k8ssvc.K8sService
-	serviceAccountsSvc   serviceaccountssvc.ServiceAccountsService
-	ldapGroupBindingsSvc ldapgroupbindingssvc.LdapGroupBindingService
-	ldapSvc              ldapsvc.LDAPSvc
-	logger               *slog.Logger
+	env                envsvc.Env
+	serviceAccountsSvc serviceaccountssvc.ServiceAccountsService
+	certificatesSvc    certificatessvc.CertificatesService
+	tokensSvc          tokenssvc.TokensSvc
+	reconcilerSvc      reconcilersvc.ReconcilerService
+	groupsSvc          groupssvc.GroupsSvc
+	pacSvc             pacsvc.PACSvc
+	realmsSvc          realmssvc.RealmsSvc
+	logger             *slog.Logger
 }
 
 func NewProvider() func(i *do.Injector) (AuthService, error) {
 	return func(i *do.Injector) (AuthService, error) {
 		return New(
 			do.MustInvoke[envsvc.EnvSvc](i),
-			do.MustInvoke[k8ssvc.K8sService](i),
 			do.MustInvoke[serviceaccountssvc.ServiceAccountsService](i),
-			do.MustInvoke[ldapgroupbindingssvc.LdapGroupBindingService](i),
-			do.MustInvoke[ldapsvc.LDAPSvc](i),
+			do.MustInvoke[certificatessvc.CertificatesService](i),
+			do.MustInvoke[tokenssvc.TokensSvc](i),
+			do.MustInvoke[reconcilersvc.ReconcilerService](i),
+			do.MustInvoke[groupssvc.GroupsSvc](i),
+			do.MustInvoke[pacsvc.PACSvc](i),
+			do.MustInvoke[realmssvc.RealmsSvc](i),
 			do.MustInvoke[*slog.Logger](i),
 		)
 	}
@@ -45,463 +77,294 @@ func NewProvider() func(i *do.Injector) (AuthService, error) {
 
 func New(
 	configService envsvc.EnvSvc,
-	k8sSvc k8ssvc.K8sService,
 	serviceAccountsSvc serviceaccountssvc.ServiceAccountsService,
-	ldapGroupBindingsSvc ldapgroupbindingssvc.LdapGroupBindingService,
-	ldapSvc ldapsvc.LDAPSvc,
+	certificatesSvc certificatessvc.CertificatesService,
+	tokensSvc tokenssvc.TokensSvc,
+	reconcilerSvc reconcilersvc.ReconcilerService,
+	groupsSvc groupssvc.GroupsSvc,
+	pacSvc pacsvc.PACSvc,
+	realmsSvc realmssvc.RealmsSvc,
 	logger *slog.Logger,
 ) (AuthService, error) {
 	return &authService{
-		env:                  configService.GetEnv(),
-		k8sSvc:               k8sSvc,
-		serviceAccountsSvc:   serviceAccountsSvc,
-		ldapGroupBindingsSvc: ldapGroupBindingsSvc,
-		ldapSvc:              ldapSvc,
-		logger:               logger.With("service", "auth"),
+		env:                configService.GetEnv(),
+		serviceAccountsSvc: serviceAccountsSvc,
+		certificatesSvc:    certificatesSvc,
+		tokensSvc:          tokensSvc,
+		reconcilerSvc:      reconcilerSvc,
+		groupsSvc:          groupsSvc,
+		pacSvc:             pacSvc,
+		realmsSvc:          realmsSvc,
+		logger:             logger.With("service", "auth"),
 	}, nil
 }
 
 func (s *authService) AuthAccount(
 	ctx context.Context,
-	username string,
+	principal string,
+	apiVersion string,
 ) (*k8smodels.Credentials, error) {
-	s.logger.Info("Authenticating user", "username", username)
-	sa, err := s.serviceAccountsSvc.UpsertServiceAccount(ctx, username)
-	if err != nil {
-		s.logger.Error("Failed to upsert service account", "username", username, "error", err)
-		return nil, huma.Error500InternalServerError("Failed to upsert service account")
+	if !slices.Contains(k8smodels.ExecCredentialVersions, apiVersion) {
+		return nil, huma.Error400BadRequest(fmt.Sprintf(
+			"unsupported ExecCredential apiVersion %q, expected one of %s",
+			apiVersion,
+			strings.Join(k8smodels.ExecCredentialVersions, ", "),
+		))
 	}
 
-	// in case of LDAP we first try to get the user from LDAP
-	if s.env.LDAPEnabled {
-		err := s.ldapReconcilate(ctx, username, sa)
-		if err != nil {
-			return nil, err
-		}
+	s.logger.Info("Authenticating user", "principal", principal, "apiVersion", apiVersion)
+	// a ticket stays valid after its directory account is disabled
+	if err := s.CheckAccount(principal); err != nil {
+		return nil, err
 	}
 
-	token, err := s.serviceAccountsSvc.IssueToken(ctx, sa.Name)
+	var status *k8smodels.Status
+	var err error
+	switch s.env.CredentialMode {
+	case envsvc.CredentialModeCertificate:
+		status, err = s.issueCertificate(ctx, principal)
+	case envsvc.CredentialModeWebhook, envsvc.CredentialModeOIDC:
+		status, err = s.issueSignedToken(ctx, principal)
+	default:
+		status, err = s.issueToken(ctx, principal)
+	}
 	if err != nil {
-		s.logger.Error("Failed to issue token", "username", username, "error", err)
-		return nil, huma.Error500InternalServerError("Failed to issue token")
+		return nil, err
 	}
-	s.logger.Info("Token issued for user", "username", username)
 
 	return &k8smodels.Credentials{
 		Kind:       "ExecCredential",
-		ApiVersion: "client.authentication.k8s.io/v1beta1",
-		Status: &k8smodels.Status{
-			Token:               token.Status.Token,
-			ExpirationTimestamp: token.Status.ExpirationTimestamp.Time,
-		},
+		ApiVersion: apiVersion,
+		Status:     status,
 	}, nil
 }
 
-func (s *authService) ldapReconcilate(
-	ctx context.Context,
-	username string,
-	sa *corev1.ServiceAccount,
-) error {
-	user, err := s.ldapSvc.GetUser(username)
-	if err != nil {
-		s.logger.Error("Failed to get user from LDAP", "username", username, "error", err)
-		return huma.Error401Unauthorized("failed to authenticate user on LDAP")
+// CheckAccount refuses principals whose directory account is disabled, locked or expired
+func (s *authService) CheckAccount(principal string) error {
+	err := s.groupsSvc.CheckAccount(principal)
+	if err == nil {
+		return nil
 	}
-	groups, err := s.ldapSvc.GetUserGroups(user.DN)
-	if err != nil {
-		s.logger.Error(
-			"Failed to get user groups from LDAP",
-			"username",
-			username,
-			"error",
-			err,
-		)
-		return huma.Error401Unauthorized("failed to authenticate user on LDAP")
-	}
-	s.logger.Info(
-		"User authenticated via LDAP",
-		"username",
-		username,
-		"dn",
-		user.DN,
-		"groups",
-		groups,
-	)
-	groupsMap := make(map[string]bool)
-	for _, group := range groups {
-		groupsMap[group] = true
+	if errors.Is(err, groupssvc.ErrAccountDisabled) {
+		s.logger.Warn("Rejected disabled account", "principal", principal, "error", err)
+		return huma.Error403Forbidden("directory account is disabled or locked")
 	}
+	s.logger.Error("Failed to check account state", "principal", principal, "error", err)
+	return huma.Error500InternalServerError("Failed to check account state")
+}
 
-	ldapBindings := s.ldapGroupBindingsSvc.GetBindings()
-	// Filter bindings for the user
-	var userBindings []*v1.LdapGroupBinding
-	for _, binding := range ldapBindings {
-		if _, exists := groupsMap[binding.Spec.LdapGroupDN]; exists {
-			userBindings = append(userBindings, binding)
-		}
+func (s *authService) issueToken(ctx context.Context, principal string) (*k8smodels.Status, error) {
+	account, err := s.ReconcileAccount(ctx, principal)
+	if err != nil {
+		return nil, err
 	}
 
-	// Reconcile cluster role bindings for the service account
-	err = s.reconcileClusterAndRoleBindings(ctx, sa.Name, userBindings)
+	token, err := s.serviceAccountsSvc.IssueToken(ctx, account)
 	if err != nil {
-		s.logger.Error(
-			"Failed to reconcile cluster role bindings",
-			"username",
-			username,
-			"error",
-			err,
-		)
-		return huma.Error500InternalServerError(
-			"Failed to reconcile cluster role bindings",
-		)
+		s.logger.Error("Failed to issue token", "serviceAccount", account, "error", err)
+		return nil, huma.Error500InternalServerError("Failed to issue token")
 	}
-	return nil
-}
-
-func (s *authService) reconcileClusterAndRoleBindings(
-	ctx context.Context,
-	saName string,
-	ldapGroupBindings []*v1.LdapGroupBinding,
-) error {
 	s.logger.Info(
-		"Starting reconciliation of ClusterRoleBindings and RoleBindings for ServiceAccount",
-		"serviceAccount",
-		saName,
+		"Token issued for user",
+		"principal", principal,
+		"serviceAccount", account,
 	)
 
-	// ------------------------------
-	// 1. Retrieve current state
-	// ------------------------------
-	clusterRoleBindingsMap, err := s.getExistingClusterRoleBindings(ctx, saName)
-	if err != nil {
-		return err
-	}
+	return &k8smodels.Status{
+		Token:               token.Status.Token,
+		ExpirationTimestamp: token.Status.ExpirationTimestamp.Time,
+	}, nil
+}
 
-	roleBindingsMap, err := s.getExistingRoleBindings(ctx, saName)
+// issueCertificate issues a client certificate carrying the principal and its groups,
+// which are bound as Group subjects instead of through a service account.
+func (s *authService) issueCertificate(
+	ctx context.Context,
+	principal string,
+) (*k8smodels.Status, error) {
+	groups, _, err := s.ResolveGroups(ctx, principal)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// ------------------------------
-	// 2. Ensure desired bindings exist
-	// ------------------------------
-	for _, ldapGroupBinding := range ldapGroupBindings {
-		for _, binding := range ldapGroupBinding.Spec.Bindings {
-
-			bindingName := serviceaccountssvc.GenBindingName(
-				saName,
-				binding.Name,
-				ldapGroupBinding.Name,
-			)
-
-			switch binding.Kind {
-			case "ClusterRole":
-				err = s.ensureClusterRoleBinding(
-					ctx,
-					saName,
-					ldapGroupBinding.Name,
-					binding,
-					bindingName,
-					clusterRoleBindingsMap,
-				)
-				if err != nil {
-					return err
-				}
-
-			case "Role":
-				err = s.ensureRoleBinding(
-					ctx,
-					saName,
-					ldapGroupBinding.Name,
-					binding,
-					bindingName,
-					roleBindingsMap,
-				)
-				if err != nil {
-					return err
-				}
-
-			default:
-				s.logger.Warn(
-					"Skipping unsupported binding kind",
-					"serviceAccount", saName,
-					"kind", binding.Kind,
-					"name", binding.Name,
-				)
-			}
+	certificate, err := s.certificatesSvc.IssueClientCertificate(ctx, principal, groups)
+	if err != nil {
+		s.logger.Error("Failed to issue certificate", "principal", principal, "error", err)
+		if errors.Is(err, certificatessvc.ErrReservedIdentity) {
+			return nil, huma.Error403Forbidden("principal maps to a reserved identity")
 		}
+		return nil, huma.Error500InternalServerError("Failed to issue certificate")
 	}
+	s.logger.Info("Certificate issued for user", "principal", principal)
 
-	// ------------------------------
-	// 3. Remove bindings no longer needed
-	// ------------------------------
-	err = s.removeUnusedClusterRoleBindings(ctx, saName, clusterRoleBindingsMap)
+	return &k8smodels.Status{
+		ClientCertificateData: string(certificate.CertificatePEM),
+		ClientKeyData:         string(certificate.KeyPEM),
+		ExpirationTimestamp:   certificate.NotAfter,
+	}, nil
+}
+
+// issueSignedToken issues a token signed by kerbernetes carrying the principal and its
+// groups, which the API server checks through the TokenReview webhook or as an ID token
+// of the OIDC issuer.
+func (s *authService) issueSignedToken(
+	ctx context.Context,
+	principal string,
+) (*k8smodels.Status, error) {
+	groups, _, err := s.ResolveGroups(ctx, principal)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = s.removeUnusedRoleBindings(ctx, saName, roleBindingsMap)
+	issue := s.tokensSvc.Issue
+	if s.env.CredentialMode == envsvc.CredentialModeOIDC {
+		issue = s.tokensSvc.IssueIDToken
+	}
+	token, expiresAt, err := issue(principal, groups)
 	if err != nil {
-		return err
+		s.logger.Error("Failed to issue signed token", "principal", principal, "error", err)
+		switch {
+		case errors.Is(err, tokenssvc.ErrReservedIdentity):
+			return nil, huma.Error403Forbidden("principal maps to a reserved identity")
+		case errors.Is(err, signingkeyssvc.ErrNoSigningKey):
+			return nil, huma.Error503ServiceUnavailable("signing keys are not loaded yet")
+		}
+		return nil, huma.Error500InternalServerError("Failed to issue token")
 	}
-
 	s.logger.Info(
-		"Completed reconciliation of ClusterRoleBindings and RoleBindings",
-		"serviceAccount", saName,
-		"remainingClusterRoleBindings", len(clusterRoleBindingsMap),
-		"remainingRoleBindings", len(roleBindingsMap),
+		"Signed token issued for user",
+		"principal", principal,
+		"mode", s.env.CredentialMode,
+		"groups", len(groups),
 	)
-	return nil
-}
 
-//
-// -------- Helper functions --------
-//
+	return &k8smodels.Status{
+		Token:               token,
+		ExpirationTimestamp: expiresAt,
+	}, nil
+}
 
-func (s *authService) getExistingClusterRoleBindings(
+func (s *authService) ReconcileAccount(
 	ctx context.Context,
-	saName string,
-) (map[string]rbacv1.ClusterRoleBinding, error) {
-	clusterRoleBindings, err := s.serviceAccountsSvc.GetClusterRoleBindings(ctx, saName)
+	principal string,
+) (types.NamespacedName, error) {
+	realm, err := s.resolveRealm(principal)
 	if err != nil {
-		s.logger.Error(
-			"Unable to retrieve current ClusterRoleBindings",
-			"serviceAccount", saName,
-			"error", err,
-		)
-		return nil, huma.Error500InternalServerError("failed to get cluster role bindings")
-	}
-
-	result := make(map[string]rbacv1.ClusterRoleBinding, len(clusterRoleBindings))
-	for _, b := range clusterRoleBindings {
-		result[b.Name] = b
+		return types.NamespacedName{}, err
 	}
-	return result, nil
-}
 
-func (s *authService) getExistingRoleBindings(
-	ctx context.Context,
-	saName string,
-) (map[string]rbacv1.RoleBinding, error) {
-	roleBindings, err := s.serviceAccountsSvc.GetRoleBindings(ctx, saName)
+	sa, err := s.serviceAccountsSvc.UpsertServiceAccount(
+		ctx,
+		principal,
+		realm.Namespace,
+		realm.Prefix,
+	)
 	if err != nil {
-		s.logger.Error(
-			"Unable to retrieve current RoleBindings",
-			"serviceAccount", saName,
-			"error", err,
+		s.logger.Error("Failed to upsert service account", "principal", principal, "error", err)
+		return types.NamespacedName{}, huma.Error500InternalServerError(
+			"Failed to upsert service account",
 		)
-		return nil, huma.Error500InternalServerError("failed to get role bindings")
 	}
+	account := serviceaccountssvc.Ref(sa)
 
-	result := make(map[string]rbacv1.RoleBinding, len(roleBindings))
-	for _, b := range roleBindings {
-		result[b.Name] = b
+	groups, ok, err := s.ResolveGroups(ctx, principal)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+	if !ok {
+		return account, nil
 	}
-	return result, nil
-}
 
-func (s *authService) ensureClusterRoleBinding(
-	ctx context.Context,
-	saName, ldapGroupBindingName string,
-	binding v1.LdapGroupBindingItem,
-	bindingName string,
-	existingMap map[string]rbacv1.ClusterRoleBinding,
-) error {
-	existing, exists := existingMap[bindingName]
-
-	if !exists {
-		newBinding, err := s.serviceAccountsSvc.CreateClusterRoleBinding(
-			ctx,
-			saName,
-			binding.Name,
-			ldapGroupBindingName,
+	err = s.reconcilerSvc.ReconcileServiceAccount(ctx, account, groups)
+	if err != nil {
+		s.logger.Error(
+			"Failed to reconcile cluster role bindings",
+			"principal",
+			principal,
+			"error",
+			err,
 		)
-		if err != nil {
-			s.logger.Error(
-				"Failed to create missing ClusterRoleBinding",
-				"serviceAccount", saName,
-				"role", binding.Name,
-				"error", err,
-			)
-			return huma.Error500InternalServerError("failed to create cluster role binding")
-		}
-		s.logger.Info(
-			"Created new ClusterRoleBinding",
-			"serviceAccount", saName,
-			"bindingName", newBinding.Name,
+		return types.NamespacedName{}, huma.Error500InternalServerError(
+			"Failed to reconcile cluster role bindings",
 		)
-	} else {
-		if existing.RoleRef.Name != binding.Name {
-			_, err := s.serviceAccountsSvc.UpdateClusterRoleBinding(
-				ctx,
-				saName,
-				binding.Name,
-				ldapGroupBindingName,
-			)
-			if err != nil {
-				s.logger.Error(
-					"Failed to update ClusterRoleBinding to match desired state",
-					"serviceAccount", saName,
-					"role", binding.Name,
-					"error", err,
-				)
-				return huma.Error500InternalServerError("failed to update cluster role binding")
-			}
-			s.logger.Info(
-				"Updated ClusterRoleBinding to match desired state",
-				"serviceAccount", saName,
-				"bindingName", existing.Name,
-			)
-		}
 	}
-
-	// delete from existing map to track unused bindings
-	delete(existingMap, bindingName)
-	return nil
+	return account, nil
 }
 
-func (s *authService) ensureRoleBinding(
+func (s *authService) ResolveGroups(
 	ctx context.Context,
-	saName, ldapGroupBindingName string,
-	binding v1.LdapGroupBindingItem,
-	bindingName string,
-	existingMap map[string]rbacv1.RoleBinding,
-) error {
-	if binding.Namespace == "" {
-		s.logger.Warn(
-			"Skipping RoleBinding creation/update due to missing namespace",
-			"serviceAccount", saName,
-			"roleName", binding.Name,
-		)
-		return nil
+	principal string,
+) ([]string, bool, error) {
+	if _, err := s.resolveRealm(principal); err != nil {
+		return nil, false, err
 	}
 
-	existing, exists := existingMap[bindingName]
-	roleRef := rbacv1.RoleRef{
-		APIGroup: binding.ApiGroup,
-		Kind:     binding.Kind,
-		Name:     binding.Name,
+	// groups carried by the ticket PAC take precedence over the group providers
+	if sids, ok := security.GetGroupSIDsFromContext(ctx); s.env.PACEnabled && ok {
+		groups, err := s.pacGroups(principal, sids)
+		return groups, true, err
 	}
-
-	saNamespace := s.k8sSvc.GetNamespace()
-
-	if !exists {
-		newBinding, err := s.serviceAccountsSvc.CreateRoleBinding(
-			ctx,
-			saName,
-			binding.Namespace,
-			ldapGroupBindingName,
-			roleRef,
-		)
-		if err != nil {
-			s.logger.Error(
-				"Failed to create missing RoleBinding",
-				"serviceAccount", saName,
-				"role", binding.Name,
-				"namespace", binding.Namespace,
-				"error", err,
-			)
-			return huma.Error500InternalServerError("failed to create role binding")
-		}
-		s.logger.Info(
-			"Created new RoleBinding",
-			"serviceAccount", saName,
-			"bindingName", newBinding.Name,
-		)
-	} else {
-		subjectDiffer := false
-		if len(existing.Subjects) != 1 {
-			subjectDiffer = true
-		} else {
-			subject := existing.Subjects[0]
-			if subject.Kind != "ServiceAccount" ||
-				subject.Name != saName ||
-				subject.Namespace != saNamespace {
-				subjectDiffer = true
-			}
-		}
-		if subjectDiffer ||
-			existing.RoleRef.Name != binding.Name ||
-			existing.RoleRef.Kind != binding.Kind ||
-			existing.RoleRef.APIGroup != binding.ApiGroup {
-			_, err := s.serviceAccountsSvc.UpdateRoleBinding(
-				ctx,
-				saName,
-				binding.Namespace,
-				roleRef,
-				ldapGroupBindingName,
-			)
-			if err != nil {
-				s.logger.Error(
-					"Failed to update RoleBinding to match desired state",
-					"serviceAccount", saName,
-					"role", binding.Name,
-					"namespace", binding.Namespace,
-					"error", err,
-				)
-				return huma.Error500InternalServerError("failed to update role binding")
-			}
-			s.logger.Info(
-				"Updated RoleBinding to match desired state",
-				"serviceAccount", saName,
-				"bindingName", existing.Name,
-			)
-		}
+	if s.groupsSvc.Enabled() {
+		groups, err := s.providerGroups(principal)
+		return groups, true, err
 	}
+	return nil, false, nil
+}
 
-	// delete from existing map to track unused bindings
-	delete(existingMap, bindingName)
-	return nil
+// resolveRealm returns the settings of the realm of a principal, rejecting denied realms.
+func (s *authService) resolveRealm(principal string) (realmssvc.Realm, error) {
+	_, realmName := security.SplitPrincipal(principal)
+	realm, err := s.realmsSvc.Resolve(realmName)
+	if err != nil {
+		s.logger.Warn("Rejected principal of denied realm", "principal", principal)
+		return realmssvc.Realm{}, huma.Error403Forbidden("realm is not allowed")
+	}
+	return realm, nil
 }
 
-func (s *authService) removeUnusedClusterRoleBindings(
-	ctx context.Context,
-	saName string,
-	bindings map[string]rbacv1.ClusterRoleBinding,
-) error {
-	for name, binding := range bindings {
-		s.logger.Info(
-			"Removing ClusterRoleBinding not present in desired state",
-			"serviceAccount", saName,
-			"name", name,
-			"role", binding.Name,
+func (s *authService) providerGroups(principal string) ([]string, error) {
+	groups, err := s.groupsSvc.GetGroups(principal)
+	if err != nil {
+		s.logger.Error(
+			"Failed to get user groups",
+			"principal",
+			principal,
+			"providers",
+			s.groupsSvc.Name(),
+			"error",
+			err,
 		)
-		err := s.serviceAccountsSvc.DeleteClusterRoleBinding(ctx, name)
-		if err != nil {
-			s.logger.Error(
-				"Failed to remove unused ClusterRoleBinding",
-				"serviceAccount", saName,
-				"name", name,
-				"error", err,
-			)
-			return huma.Error500InternalServerError("failed to delete cluster role binding")
+		if errors.Is(err, groupssvc.ErrUserNotFound) {
+			return nil, huma.Error401Unauthorized("user is unknown to the group providers")
 		}
+		return nil, huma.Error401Unauthorized("failed to resolve user groups")
 	}
-	return nil
+	s.logger.Info(
+		"User groups resolved",
+		"principal",
+		principal,
+		"providers",
+		s.groupsSvc.Name(),
+		"groups",
+		groups,
+	)
+	return groups, nil
 }
 
-func (s *authService) removeUnusedRoleBindings(
-	ctx context.Context,
-	saName string,
-	bindings map[string]rbacv1.RoleBinding,
-) error {
-	for name, binding := range bindings {
-		s.logger.Info(
-			"Removing RoleBinding not present in desired state",
-			"serviceAccount", saName,
-			"name", name,
-			"role", binding.Name,
-		)
-		err := s.serviceAccountsSvc.DeleteRoleBinding(ctx, binding.Namespace, name)
-		if err != nil {
-			s.logger.Error(
-				"Failed to remove unused RoleBinding",
-				"serviceAccount", saName,
-				"name", name,
-				"error", err,
-			)
-			return huma.Error500InternalServerError("failed to delete role binding")
-		}
+func (s *authService) pacGroups(principal string, sids []string) ([]string, error) {
+	groups, err := s.pacSvc.ResolveGroups(sids)
+	if err != nil {
+		s.logger.Error("Failed to resolve PAC group SIDs", "principal", principal, "error", err)
+		return nil, huma.Error500InternalServerError("Failed to resolve PAC groups")
 	}
-	return nil
+	s.logger.Info(
+		"User groups resolved from PAC",
+		"principal",
+		principal,
+		"sids",
+		len(sids),
+		"groups",
+		groups,
+	)
+	return groups, nil
 }
@@ -0,0 +1,202 @@
+package clusterssvc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authsvc "github.com/froz42/kerbernetes/internal/services/auth"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	certificatessvc "github.com/froz42/kerbernetes/internal/services/k8s/certificates"
+	ldapgroupbindingssvc "github.com/froz42/kerbernetes/internal/services/k8s/ldapgroupbindings"
+	namespacessvc "github.com/froz42/kerbernetes/internal/services/k8s/namespaces"
+	serviceaccountssvc "github.com/froz42/kerbernetes/internal/services/k8s/serviceaccounts"
+	kubeconfigsvc "github.com/froz42/kerbernetes/internal/services/kubeconfig"
+	reconcilersvc "github.com/froz42/kerbernetes/internal/services/reconciler"
+	tokenssvc "github.com/froz42/kerbernetes/internal/services/tokens"
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// syncTimeout bounds the wait for the caches of a cluster, an unreachable cluster is
+// retried with a backoff
+const syncTimeout = 30 * time.Second
+
+// runningCluster is a cluster whose services run until cancel is called
+type runningCluster struct {
+	cluster       *Cluster
+	generation    int64
+	secretVersion string
+	cancel        context.CancelFunc
+}
+
+// restConfig reads the kubeconfig of a cluster from its Secret, it also returns the Secret
+// resource version to detect changes.
+func (svc *clustersSvc) restConfig(
+	ctx context.Context,
+	cluster *v1.KerbernetesCluster,
+) (*rest.Config, string, error) {
+	ref := cluster.Spec.KubeconfigSecretRef
+	key := ref.Key
+	if key == "" {
+		key = "kubeconfig"
+	}
+
+	secret, err := svc.clientset.CoreV1().
+		Secrets(svc.namespace).
+		Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get kubeconfig secret: %w", err)
+	}
+	raw, ok := secret.Data[key]
+	if !ok {
+		return nil, "", fmt.Errorf("secret %s has no key %s", ref.Name, key)
+	}
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	return restConfig, secret.ResourceVersion, nil
+}
+
+// clusterEnv returns the configuration of the services of a cluster
+func (svc *clustersSvc) clusterEnv(cluster *v1.KerbernetesCluster) envsvc.Env {
+	env := svc.env
+	env.KubeconfigServer = cluster.Spec.Server
+	env.KubeconfigCAPath = ""
+	env.KubeconfigClusterName = cluster.Name
+	// webhook and ID tokens are signed with keys shared by every cluster, a token of a
+	// cluster must not be accepted by the others
+	switch env.CredentialMode {
+	case envsvc.CredentialModeWebhook:
+		env.TokenAudience = clusterAudience(cluster)
+	case envsvc.CredentialModeOIDC:
+		env.OIDCClientID = clusterAudience(cluster)
+	default:
+		if cluster.Spec.TokenAudience != "" {
+			env.TokenAudience = cluster.Spec.TokenAudience
+		}
+	}
+	// the impersonating proxy only reaches the cluster kerbernetes runs in
+	env.ProxyEnabled = false
+	return env
+}
+
+// clusterAudience returns the audience of the webhook and ID tokens issued for a cluster
+func clusterAudience(cluster *v1.KerbernetesCluster) string {
+	if cluster.Spec.TokenAudience != "" {
+		return cluster.Spec.TokenAudience
+	}
+	return "kerbernetes:" + cluster.Name
+}
+
+// run builds the services of a cluster against its API server and starts them, it
+// returns once their caches are synced.
+func (svc *clustersSvc) run(
+	ctx context.Context,
+	cluster *v1.KerbernetesCluster,
+	restConfig *rest.Config,
+) (*runningCluster, error) {
+	env := svc.clusterEnv(cluster)
+	envSvc := envsvc.NewStatic(env)
+	logger := svc.logger.With("cluster", cluster.Name)
+
+	namespace := cluster.Spec.Namespace
+	if namespace == "" {
+		namespace = svc.namespace
+	}
+	k8sSvc, err := k8ssvc.NewForConfig(logger, env, restConfig, namespace)
+	if err != nil {
+		return nil, err
+	}
+	serviceAccountsSvc, err := serviceaccountssvc.New(env, k8sSvc, logger)
+	if err != nil {
+		return nil, err
+	}
+	ldapGroupBindingsSvc, err := ldapgroupbindingssvc.New(logger, k8sSvc)
+	if err != nil {
+		return nil, err
+	}
+	namespacesSvc, err := namespacessvc.New(logger, k8sSvc)
+	if err != nil {
+		return nil, err
+	}
+	certificatesSvc, err := certificatessvc.New(envSvc, k8sSvc, logger)
+	if err != nil {
+		return nil, err
+	}
+	reconcilerSvc, err := reconcilersvc.New(
+		envSvc,
+		k8sSvc,
+		serviceAccountsSvc,
+		ldapGroupBindingsSvc,
+		namespacesSvc,
+		svc.ldapSvc,
+		svc.groupsSvc,
+		svc.realmsSvc,
+		logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+	tokensSvc, err := tokenssvc.New(envSvc, svc.signingKeysSvc, logger)
+	if err != nil {
+		return nil, err
+	}
+	authSvc, err := authsvc.New(
+		envSvc,
+		serviceAccountsSvc,
+		certificatesSvc,
+		tokensSvc,
+		reconcilerSvc,
+		svc.groupsSvc,
+		svc.pacSvc,
+		svc.realmsSvc,
+		logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+	kubeconfigSvc, err := kubeconfigsvc.New(envSvc, k8sSvc, authSvc, serviceAccountsSvc, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterCtx, cancel := context.WithCancel(ctx)
+	for _, start := range []func(context.Context) error{
+		ldapGroupBindingsSvc.Start,
+		namespacesSvc.Start,
+		reconcilerSvc.Start,
+	} {
+		go func() {
+			if err := start(clusterCtx); err != nil {
+				logger.Error("Cluster service stopped", "error", err)
+			}
+		}()
+	}
+
+	syncCtx, syncCancel := context.WithTimeout(clusterCtx, syncTimeout)
+	defer syncCancel()
+	if !cache.WaitForCacheSync(
+		syncCtx.Done(),
+		ldapGroupBindingsSvc.HasSynced,
+		namespacesSvc.HasSynced,
+	) {
+		cancel()
+		return nil, fmt.Errorf("cluster API server did not sync within %s", syncTimeout)
+	}
+
+	return &runningCluster{
+		cluster: &Cluster{
+			Name:       cluster.Name,
+			Auth:       authSvc,
+			Kubeconfig: kubeconfigSvc,
+		},
+		generation: cluster.Generation,
+		cancel:     cancel,
+	}, nil
+}
@@ -0,0 +1,260 @@
+package clusterssvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	authsvc "github.com/froz42/kerbernetes/internal/services/auth"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	groupssvc "github.com/froz42/kerbernetes/internal/services/groups"
+	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	signingkeyssvc "github.com/froz42/kerbernetes/internal/services/k8s/signingkeys"
+	kubeconfigsvc "github.com/froz42/kerbernetes/internal/services/kubeconfig"
+	ldapsvc "github.com/froz42/kerbernetes/internal/services/ldap"
+	pacsvc "github.com/froz42/kerbernetes/internal/services/pac"
+	realmssvc "github.com/froz42/kerbernetes/internal/services/realms"
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	clientset "github.com/froz42/kerbernetes/k8s/generated/clientset/versioned"
+	informers "github.com/froz42/kerbernetes/k8s/generated/informers/externalversions"
+	listers "github.com/froz42/kerbernetes/k8s/generated/listers/rbac.kerbernetes.io/v1"
+	"github.com/samber/do"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// ErrClusterNotFound is returned for a cluster that is not registered or not ready
+var ErrClusterNotFound = errors.New("cluster is not registered or not ready")
+
+// Cluster holds the services issuing credentials for a registered cluster
+type Cluster struct {
+	Name       string
+	Auth       authsvc.AuthService
+	Kubeconfig kubeconfigsvc.KubeconfigSvc
+}
+
+type ClustersSvc interface {
+	// Start watches the KerbernetesClusters and runs the services of every registered
+	// cluster until the context is cancelled. It returns immediately when disabled.
+	Start(ctx context.Context) error
+
+	// Get returns the services of a ready registered cluster
+	Get(name string) (*Cluster, error)
+}
+
+type clustersSvc struct {
+	env       envsvc.Env
+	clientset *kubernetes.Clientset
+	crdClient *clientset.Clientset
+	namespace string
+	groupsSvc groupssvc.GroupsSvc
+	ldapSvc   ldapsvc.LDAPSvc
+	pacSvc    pacsvc.PACSvc
+	realmsSvc realmssvc.RealmsSvc
+	logger    *slog.Logger
+
+	signingKeysSvc signingkeyssvc.SigningKeysSvc
+
+	informerFactory informers.SharedInformerFactory
+	informer        cache.SharedIndexInformer
+	lister          listers.KerbernetesClusterLister
+	queue           workqueue.TypedRateLimitingInterface[string]
+
+	mu       sync.RWMutex
+	clusters map[string]*runningCluster
+}
+
+func NewProvider() func(i *do.Injector) (ClustersSvc, error) {
+	return func(i *do.Injector) (ClustersSvc, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[k8ssvc.K8sService](i),
+			do.MustInvoke[groupssvc.GroupsSvc](i),
+			do.MustInvoke[ldapsvc.LDAPSvc](i),
+			do.MustInvoke[pacsvc.PACSvc](i),
+			do.MustInvoke[realmssvc.RealmsSvc](i),
+			do.MustInvoke[signingkeyssvc.SigningKeysSvc](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+func New(
+	envSvc envsvc.EnvSvc,
+	k8sSvc k8ssvc.K8sService,
+	groupsSvc groupssvc.GroupsSvc,
+	ldapSvc ldapsvc.LDAPSvc,
+	pacSvc pacsvc.PACSvc,
+	realmsSvc realmssvc.RealmsSvc,
+	signingKeysSvc signingkeyssvc.SigningKeysSvc,
+	logger *slog.Logger,
+) (ClustersSvc, error) {
+	env := envSvc.GetEnv()
+	crdClient, err := clientset.NewForConfig(k8sSvc.GetRestConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	// the resync picks up kubeconfig Secret changes, which are not watched
+	informerFactory := informers.NewSharedInformerFactory(
+		crdClient,
+		time.Duration(env.ClustersResyncInterval)*time.Second,
+	)
+	informer := informerFactory.RbacKerbernetes().V1().KerbernetesClusters()
+
+	svc := &clustersSvc{
+		env:             env,
+		clientset:       k8sSvc.GetClientset(),
+		crdClient:       crdClient,
+		namespace:       k8sSvc.GetNamespace(),
+		groupsSvc:       groupsSvc,
+		ldapSvc:         ldapSvc,
+		pacSvc:          pacSvc,
+		realmsSvc:       realmsSvc,
+		signingKeysSvc:  signingKeysSvc,
+		logger:          logger.With("service", "clusters"),
+		informerFactory: informerFactory,
+		informer:        informer.Informer(),
+		lister:          informer.Lister(),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "clusters"},
+		),
+		clusters: make(map[string]*runningCluster),
+	}
+	return svc, nil
+}
+
+func (svc *clustersSvc) Get(name string) (*Cluster, error) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+	running, ok := svc.clusters[name]
+	if !ok {
+		return nil, ErrClusterNotFound
+	}
+	return running.cluster, nil
+}
+
+func (svc *clustersSvc) Start(ctx context.Context) error {
+	if !svc.env.ClustersEnabled {
+		return nil
+	}
+	defer svc.queue.ShutDown()
+
+	enqueue := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if cluster, ok := obj.(*v1.KerbernetesCluster); ok {
+			svc.queue.Add(cluster.Name)
+		}
+	}
+	_, err := svc.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(_, newObj interface{}) {
+			enqueue(newObj)
+		},
+		DeleteFunc: enqueue,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch KerbernetesClusters: %w", err)
+	}
+
+	svc.informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), svc.informer.HasSynced) {
+		return fmt.Errorf("failed to wait for KerbernetesCluster cache sync")
+	}
+	svc.logger.Info("Watching KerbernetesClusters")
+
+	go svc.runWorker(ctx)
+	<-ctx.Done()
+
+	svc.mu.Lock()
+	for name, running := range svc.clusters {
+		running.cancel()
+		delete(svc.clusters, name)
+	}
+	svc.mu.Unlock()
+	return nil
+}
+
+func (svc *clustersSvc) runWorker(ctx context.Context) {
+	for {
+		name, shutdown := svc.queue.Get()
+		if shutdown {
+			return
+		}
+
+		err := svc.sync(ctx, name)
+		if err != nil {
+			svc.logger.Error("Failed to load cluster", "cluster", name, "error", err)
+			svc.queue.AddRateLimited(name)
+		} else {
+			svc.queue.Forget(name)
+		}
+		svc.queue.Done(name)
+	}
+}
+
+// sync starts the services of a registered cluster, restarting them when its spec or
+// kubeconfig changed, and stops them once it is deleted.
+func (svc *clustersSvc) sync(ctx context.Context, name string) error {
+	cluster, err := svc.lister.Get(name)
+	if apierrors.IsNotFound(err) {
+		svc.stop(name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	restConfig, version, err := svc.restConfig(ctx, cluster)
+	if err != nil {
+		svc.stop(name)
+		svc.updateStatus(ctx, cluster, err)
+		return err
+	}
+
+	svc.mu.RLock()
+	current, ok := svc.clusters[name]
+	svc.mu.RUnlock()
+	if ok && current.generation == cluster.Generation && current.secretVersion == version {
+		return nil
+	}
+
+	running, err := svc.run(ctx, cluster, restConfig)
+	if err != nil {
+		svc.stop(name)
+		svc.updateStatus(ctx, cluster, err)
+		return err
+	}
+	running.secretVersion = version
+
+	svc.mu.Lock()
+	previous := svc.clusters[name]
+	svc.clusters[name] = running
+	svc.mu.Unlock()
+	if previous != nil {
+		previous.cancel()
+	}
+
+	svc.logger.Info("Cluster ready", "cluster", name, "server", restConfig.Host)
+	svc.updateStatus(ctx, cluster, nil)
+	return nil
+}
+
+func (svc *clustersSvc) stop(name string) {
+	svc.mu.Lock()
+	running, ok := svc.clusters[name]
+	delete(svc.clusters, name)
+	svc.mu.Unlock()
+	if ok {
+		running.cancel()
+		svc.logger.Info("Stopped cluster", "cluster", name)
+	}
+}
@@ -0,0 +1,51 @@
+package clusterssvc
+
+import (
+	"context"
+
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// updateStatus records whether the services of a cluster are running, loadErr is the
+// reason they are not.
+func (svc *clustersSvc) updateStatus(
+	ctx context.Context,
+	cluster *v1.KerbernetesCluster,
+	loadErr error,
+) {
+	condition := metav1.Condition{
+		Type:               v1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Connected",
+		Message:            "Credentials are issued for the cluster",
+		ObservedGeneration: cluster.Generation,
+	}
+	if loadErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "LoadFailed"
+		condition.Message = loadErr.Error()
+	}
+
+	clusters := svc.crdClient.RbacKerbernetesV1().KerbernetesClusters()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := clusters.Get(ctx, cluster.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		status := current.Status.DeepCopy()
+		status.ObservedGeneration = current.Generation
+		if !meta.SetStatusCondition(&status.Conditions, condition) &&
+			current.Status.ObservedGeneration == status.ObservedGeneration {
+			return nil
+		}
+		current.Status = *status
+		_, err = clusters.UpdateStatus(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		svc.logger.Warn("Failed to update cluster status", "cluster", cluster.Name, "error", err)
+	}
+}
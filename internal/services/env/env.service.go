@@ -1,7 +1,11 @@
 package envsvc
 
 import (
+	"errors"
+	"fmt"
+	"net/netip"
 	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/mcuadros/go-defaults"
@@ -9,27 +13,271 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Credential modes of CREDENTIAL_MODE
+const (
+	CredentialModeToken       = "token"
+	CredentialModeCertificate = "certificate"
+	CredentialModeWebhook     = "webhook"
+	CredentialModeOIDC        = "oidc"
+)
+
+// Bind modes of LDAP_BIND_MODE
+const (
+	LDAPBindModeSimple = "simple"
+	LDAPBindModeGSSAPI = "gssapi"
+)
+
+// Nested group strategies of LDAP_NESTED_GROUPS
+const (
+	NestedGroupsNone      = "none"
+	NestedGroupsInChain   = "in-chain"
+	NestedGroupsRecursive = "recursive"
+	NestedGroupsMemberOf  = "member-of"
+)
+
+// Account state checks of LDAP_ACCOUNT_CHECKS
+const (
+	AccountCheckActiveDirectory = "active-directory"
+	AccountCheckNSAccountLock   = "ns-account-lock"
+	AccountCheckPPolicy         = "ppolicy"
+	AccountCheckShadow          = "shadow"
+)
+
 // Config represents the configuration options for the service.
 type Env struct {
 	HTTPPort   int    `mapstructure:"HTTP_PORT"  default:"3000" validate:"required"`
 	APIPrefix  string `mapstructure:"API_PREFIX" default:"/api" validate:"required"`
 	KeytabPath string `mapstructure:"KEYTAB_PATH" default:"/etc/krb5.keytab" validate:"required"`
-	Namespace  string `mapstructure:"NAMESPACE" default:"default" validate:"required"`
+
+	// KeytabSecretName reads the keytab from a Secret of the kerbernetes namespace instead
+	KeytabSecretName string `mapstructure:"KEYTAB_SECRET_NAME"`
+	KeytabSecretKey  string `mapstructure:"KEYTAB_SECRET_KEY" default:"krb5.keytab"`
+	// KeytabReloadInterval is the period in seconds of the keytab file check, negative
+	// disables it
+	KeytabReloadInterval int `mapstructure:"KEYTAB_RELOAD_INTERVAL" default:"30"`
+	// KeytabRotationWindow is how long in seconds the previous keytab stays accepted, negative disables it
+	KeytabRotationWindow int `mapstructure:"KEYTAB_ROTATION_WINDOW" default:"3600"`
+
+	Namespace string `mapstructure:"NAMESPACE" default:"default" validate:"required"`
+
+	// RealmsConfigPath is a YAML or JSON realm policy, every realm is accepted when unset
+	RealmsConfigPath string `mapstructure:"REALMS_CONFIG_PATH"`
+	// DefaultRealm is the realm whose users are resolved with the default LDAP settings and
+	// the usernames of the static groups, the realm of the keytab when unset
+	DefaultRealm string `mapstructure:"DEFAULT_REALM"`
+
+	// SANameKeepRealm keeps the realm of the principal in the service account name
+	SANameKeepRealm bool `mapstructure:"SA_NAME_KEEP_REALM" default:"false"`
+
+	// CredentialMode is token to issue service account tokens, certificate to issue X.509
+	// client certificates, webhook to issue tokens checked by the TokenReview webhook or oidc
+	// to issue ID tokens of the built-in OIDC issuer, the last three carrying the principal
+	// and its groups
+	CredentialMode        string `mapstructure:"CREDENTIAL_MODE" default:"token" validate:"oneof=token certificate webhook oidc"`
+	CertificateSignerName string `mapstructure:"CERTIFICATE_SIGNER_NAME" default:"kubernetes.io/kube-apiserver-client"`
+	// CertificateDuration is the requested certificate lifetime in seconds
+	CertificateDuration int `mapstructure:"CERTIFICATE_DURATION" default:"3600" validate:"min=600"`
 
 	TokenDuration int    `mapstructure:"TOKEN_DURATION" default:"600" validate:"required"`
 	TokenAudience string `mapstructure:"TOKEN_AUDIENCE" default:"https://kubernetes.default.svc.cluster.local"`
 
-	LDAPEnabled bool   `mapstructure:"LDAP_ENABLED" default:"false"`
-	LDAPURL     string `mapstructure:"LDAP_URL"`
+	// SigningKeysSecretName is the Secret of the kerbernetes namespace holding the keys signing
+	// webhook tokens, it is created when missing
+	SigningKeysSecretName string `mapstructure:"SIGNING_KEYS_SECRET_NAME" default:"kerbernetes-signing-keys"`
+	// SigningKeyRotationInterval is the period in seconds of the signing key rotation, replaced
+	// keys are kept for verification during one more period
+	SigningKeyRotationInterval int `mapstructure:"SIGNING_KEY_ROTATION_INTERVAL" default:"86400" validate:"min=3600"`
+
+	// PublicURL is the external URL of this instance, derived from the request when unset. It
+	// is required by the OIDC issuer, whose URL must be stable.
+	PublicURL string `mapstructure:"PUBLIC_URL" validate:"required_if=CredentialMode oidc,omitempty,url"`
+	// TrustedProxies is a comma separated list of the CIDRs of the reverse proxies whose
+	// X-Forwarded-Proto and X-Forwarded-Host headers derive the URL when PublicURL is unset
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+
+	// OIDCClientID is the audience of the ID tokens, the client ID configured on the API server
+	OIDCClientID string `mapstructure:"OIDC_CLIENT_ID" default:"kerbernetes"`
 
+	// KubeconfigServer is the API server URL of generated kubeconfigs, the in-cluster one when unset
+	KubeconfigServer string `mapstructure:"KUBECONFIG_SERVER" validate:"omitempty,url"`
+	// KubeconfigCAPath is the API server CA bundle of generated kubeconfigs, the in-cluster one when unset
+	KubeconfigCAPath      string `mapstructure:"KUBECONFIG_CA_PATH"`
+	KubeconfigClusterName string `mapstructure:"KUBECONFIG_CLUSTER_NAME" default:"kubernetes"`
+	// KubeconfigExecCommand is the credential plugin users install, the client/kerbernetes script
+	KubeconfigExecCommand string `mapstructure:"KUBECONFIG_EXEC_COMMAND" default:"kerbernetes"`
+
+	// AdminPrincipals is a comma separated list of the principals allowed to use the admin
+	// endpoints
+	AdminPrincipals string `mapstructure:"ADMIN_PRINCIPALS"`
+
+	// ClustersEnabled issues credentials for the clusters registered with KerbernetesCluster
+	ClustersEnabled bool `mapstructure:"CLUSTERS_ENABLED" default:"false"`
+	// ClustersResyncInterval is the period in seconds of the registered clusters check, which
+	// picks up kubeconfig Secret changes and retries unreachable clusters
+	ClustersResyncInterval int `mapstructure:"CLUSTERS_RESYNC_INTERVAL" default:"300" validate:"min=10"`
+
+	LDAPEnabled bool `mapstructure:"LDAP_ENABLED" default:"false"`
+	// LDAPURL is a comma separated list of LDAP server URLs, tried in order
+	LDAPURL string `mapstructure:"LDAP_URL"`
+	// LDAPSRVDomain discovers the LDAP servers from the _<service>._tcp.<domain> SRV records
+	// instead of LDAPURL
+	LDAPSRVDomain string `mapstructure:"LDAP_SRV_DOMAIN"`
+	// LDAPSRVService is the SRV service looked up, which is also the URL scheme of the servers
+	LDAPSRVService string `mapstructure:"LDAP_SRV_SERVICE" default:"ldap" validate:"oneof=ldap ldaps"`
+	// LDAPQuarantineDuration is how long in seconds a failing LDAP server is only tried once
+	// every other server failed
+	LDAPQuarantineDuration int `mapstructure:"LDAP_QUARANTINE_DURATION" default:"60" validate:"min=1"`
+
+	// LDAPBindMode is simple to bind with LDAPBindDN and LDAPBindPassword, or gssapi to bind
+	// with SASL GSSAPI as the service principal of the keytab
+	LDAPBindMode     string `mapstructure:"LDAP_BIND_MODE" default:"simple" validate:"oneof=simple gssapi"`
 	LDAPBindDN       string `mapstructure:"LDAP_BIND_DN"`
 	LDAPBindPassword string `mapstructure:"LDAP_BIND_PASSWORD"`
+	// LDAPGSSAPIPrincipal is the keytab principal of the GSSAPI bind, the first one when unset
+	LDAPGSSAPIPrincipal string `mapstructure:"LDAP_GSSAPI_PRINCIPAL"`
+	// KRB5ConfPath is the Kerberos configuration locating the KDCs for the GSSAPI bind
+	KRB5ConfPath string `mapstructure:"KRB5_CONF_PATH" default:"/etc/krb5.conf"`
+
+	// LDAPStartTLS upgrades ldap:// connections to TLS before binding
+	LDAPStartTLS bool `mapstructure:"LDAP_START_TLS" default:"false"`
+	// LDAPCAPath is the CA bundle verifying the LDAP servers, the system one when unset
+	LDAPCAPath string `mapstructure:"LDAP_CA_PATH" validate:"omitempty,file"`
+	// LDAPClientCertPath and LDAPClientKeyPath authenticate to the LDAP servers with TLS
+	LDAPClientCertPath string `mapstructure:"LDAP_CLIENT_CERT_PATH" validate:"required_with=LDAPClientKeyPath,omitempty,file"`
+	LDAPClientKeyPath  string `mapstructure:"LDAP_CLIENT_KEY_PATH" validate:"required_with=LDAPClientCertPath,omitempty,file"`
+	// LDAPServerName overrides the name the LDAP server certificates are verified against,
+	// the host of the URL by default
+	LDAPServerName string `mapstructure:"LDAP_SERVER_NAME"`
+
+	// LDAPCacheTTL is how long in seconds users and their groups are cached, negative
+	// disables the cache
+	LDAPCacheTTL int `mapstructure:"LDAP_CACHE_TTL" default:"60"`
+	// LDAPCacheNegativeTTL is how long in seconds unknown users and users without groups are
+	// cached, negative disables it
+	LDAPCacheNegativeTTL int `mapstructure:"LDAP_CACHE_NEGATIVE_TTL" default:"10"`
+	// LDAPCacheMaxEntries bounds the users and the group lists cached each
+	LDAPCacheMaxEntries int `mapstructure:"LDAP_CACHE_MAX_ENTRIES" default:"10000" validate:"min=1"`
+
+	// LDAPPoolSize is the maximum number of open LDAP connections
+	LDAPPoolSize int `mapstructure:"LDAP_POOL_SIZE" default:"10" validate:"min=1"`
+	// LDAPPoolIdleTimeout is how long in seconds an unused connection is kept, negative keeps
+	// them until the server closes them
+	LDAPPoolIdleTimeout int `mapstructure:"LDAP_POOL_IDLE_TIMEOUT" default:"300"`
+	// LDAPPoolCheckInterval is how long in seconds a connection may stay unused before it is
+	// checked on reuse, negative disables the checks
+	LDAPPoolCheckInterval int `mapstructure:"LDAP_POOL_CHECK_INTERVAL" default:"30"`
 
 	LDAPUserBaseDN string `mapstructure:"LDAP_USER_BASE_DN" default:"ou=users"`
 	LDAPUserFilter string `mapstructure:"LDAP_USER_FILTER" default:"(uid=%s)"`
 
 	LDAPGroupBaseDN string `mapstructure:"LDAP_GROUP_BASE_DN" default:"ou=groups"`
 	LDAPGroupFilter string `mapstructure:"LDAP_GROUP_FILTER" default:"((member=%s)"`
+
+	// LDAPNestedGroups resolves the groups of groups a user is a member of: none keeps the
+	// groups matching the group filter, in-chain uses the Active Directory
+	// LDAP_MATCHING_RULE_IN_CHAIN on the member attribute, recursive applies the group filter
+	// to every group found and member-of follows the memberOf attribute of the user and its
+	// groups
+	LDAPNestedGroups string `mapstructure:"LDAP_NESTED_GROUPS" default:"none" validate:"oneof=none in-chain recursive member-of"`
+	// LDAPNestedGroupsMaxDepth bounds the group levels resolved by recursive and member-of,
+	// the groups of the user being the first
+	LDAPNestedGroupsMaxDepth int `mapstructure:"LDAP_NESTED_GROUPS_MAX_DEPTH" default:"10" validate:"min=1"`
+	// LDAPMemberAttribute is the group attribute listing member DNs, used by in-chain
+	LDAPMemberAttribute string `mapstructure:"LDAP_MEMBER_ATTRIBUTE" default:"member"`
+	// LDAPMemberOfAttribute is the attribute listing the groups of an entry, used by member-of
+	LDAPMemberOfAttribute string `mapstructure:"LDAP_MEMBER_OF_ATTRIBUTE" default:"memberOf"`
+
+	// LDAPAccountChecks is a comma separated list of account state checks refusing
+	// credentials to disabled accounts: active-directory (userAccountControl
+	// ACCOUNTDISABLE), ns-account-lock (FreeIPA and 389 nsAccountLock), ppolicy (OpenLDAP
+	// pwdAccountLockedTime) and shadow (expired shadowExpire)
+	LDAPAccountChecks string `mapstructure:"LDAP_ACCOUNT_CHECKS"`
+
+	// GroupProviders is a comma separated list of group providers (ldap, static), it
+	// defaults to ldap when LDAP is enabled
+	GroupProviders     string `mapstructure:"GROUP_PROVIDERS"`
+	GroupProvidersMode string `mapstructure:"GROUP_PROVIDERS_MODE" default:"union" validate:"oneof=union first-match"`
+	// StaticGroupsPath is a YAML or JSON file mapping usernames to group DNs
+	StaticGroupsPath string `mapstructure:"STATIC_GROUPS_PATH"`
+
+	// LDAPSyncInterval is the period in seconds of the background membership sync, negative
+	// disables it
+	LDAPSyncInterval int `mapstructure:"LDAP_SYNC_INTERVAL" default:"300"`
+
+	// PACEnabled resolves group memberships from the Kerberos ticket PAC instead of LDAP, which
+	// disables the background membership sync: bindings are refreshed at login only
+	PACEnabled bool `mapstructure:"KERBEROS_PAC_ENABLED" default:"false"`
+	// PACSIDMappingPath is a YAML or JSON file mapping group SIDs to group DNs
+	PACSIDMappingPath string `mapstructure:"KERBEROS_PAC_SID_MAPPING_PATH"`
+
+	// ProxyEnabled serves a proxy to the API server impersonating SPNEGO authenticated users
+	ProxyEnabled bool `mapstructure:"PROXY_ENABLED" default:"false"`
+	ProxyPort    int  `mapstructure:"PROXY_PORT" default:"6443"`
+	// ProxyCertPath and ProxyKeyPath serve the proxy over TLS, plain HTTP is served when unset
+	ProxyCertPath string `mapstructure:"PROXY_CERT_PATH" validate:"required_with=ProxyKeyPath"`
+	ProxyKeyPath  string `mapstructure:"PROXY_KEY_PATH" validate:"required_with=ProxyCertPath"`
+	// ProxyGroupCacheTTL is how long in seconds resolved groups are reused, groups in use are
+	// refreshed in the background
+	ProxyGroupCacheTTL int `mapstructure:"PROXY_GROUP_CACHE_TTL" default:"300" validate:"min=1"`
+
+	WebhookEnabled  bool   `mapstructure:"WEBHOOK_ENABLED" default:"false"`
+	WebhookPort     int    `mapstructure:"WEBHOOK_PORT" default:"9443"`
+	WebhookCertPath string `mapstructure:"WEBHOOK_CERT_PATH" default:"/etc/kerbernetes/webhook/tls.crt"`
+	WebhookKeyPath  string `mapstructure:"WEBHOOK_KEY_PATH" default:"/etc/kerbernetes/webhook/tls.key"`
+}
+
+// UsesGroupSubjects reports whether users authenticate as themselves, bound through Group
+// subjects, rather than as their service account
+func (e Env) UsesGroupSubjects() bool {
+	return e.CredentialMode != CredentialModeToken
+}
+
+// OIDCIssuerURL returns the issuer URL of the built-in OIDC issuer, under which the discovery
+// document is served
+func (e Env) OIDCIssuerURL() string {
+	return strings.TrimSuffix(e.PublicURL, "/") + e.APIPrefix + "/oidc"
+}
+
+// TrustedProxyCIDRs returns the networks of the trusted reverse proxies
+func (e Env) TrustedProxyCIDRs() ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for cidr := range strings.SplitSeq(e.TrustedProxies, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES CIDR %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// IsTrustedProxy reports whether a request comes from a trusted reverse proxy, remoteAddr
+// being the address of the peer as host:port
+func (e Env) IsTrustedProxy(remoteAddr string) bool {
+	addrPort, err := netip.ParseAddrPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+	prefixes, _ := e.TrustedProxyCIDRs()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addrPort.Addr().Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether a principal may use the admin endpoints
+func (e Env) IsAdmin(principal string) bool {
+	for admin := range strings.SplitSeq(e.AdminPrincipals, ",") {
+		if strings.TrimSpace(admin) == principal {
+			return principal != ""
+		}
+	}
+	return false
 }
 
 // ConfigService is the interface for the config service.
@@ -86,11 +334,29 @@ func New() (EnvSvc, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = env.validateLDAP()
+	if err != nil {
+		return nil, err
+	}
+	_, err = env.TrustedProxyCIDRs()
+	if err != nil {
+		return nil, err
+	}
+	// the TokenReview webhook is only served on the webhook port
+	if env.CredentialMode == CredentialModeWebhook && !env.WebhookEnabled {
+		return nil, errors.New("the webhook credential mode requires WEBHOOK_ENABLED")
+	}
 	return &configService{
 		env: *env,
 	}, nil
 }
 
+// NewStatic returns a service serving a fixed configuration, such as the one derived for a
+// registered cluster.
+func NewStatic(env Env) EnvSvc {
+	return &configService{env: env}
+}
+
 // GetConfig returns the configuration options for the service.
 func (c *configService) GetEnv() Env {
 	return c.env
@@ -0,0 +1,64 @@
+package envsvc
+
+import "testing"
+
+func TestNewIntervals(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable string
+		value    string
+		field    func(env Env) int
+		want     int
+	}{
+		{
+			name:     "unset sync interval uses the default",
+			variable: "LDAP_SYNC_INTERVAL",
+			value:    "",
+			field:    func(env Env) int { return env.LDAPSyncInterval },
+			want:     300,
+		},
+		{
+			// zero is the unset value of the defaults
+			name:     "zero sync interval uses the default",
+			variable: "LDAP_SYNC_INTERVAL",
+			value:    "0",
+			field:    func(env Env) int { return env.LDAPSyncInterval },
+			want:     300,
+		},
+		{
+			name:     "negative sync interval is kept",
+			variable: "LDAP_SYNC_INTERVAL",
+			value:    "-1",
+			field:    func(env Env) int { return env.LDAPSyncInterval },
+			want:     -1,
+		},
+		{
+			name:     "zero keytab reload interval uses the default",
+			variable: "KEYTAB_RELOAD_INTERVAL",
+			value:    "0",
+			field:    func(env Env) int { return env.KeytabReloadInterval },
+			want:     30,
+		},
+		{
+			name:     "negative keytab reload interval is kept",
+			variable: "KEYTAB_RELOAD_INTERVAL",
+			value:    "-1",
+			field:    func(env Env) int { return env.KeytabReloadInterval },
+			want:     -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.variable, tt.value)
+
+			svc, err := New()
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if got := tt.field(svc.GetEnv()); got != tt.want {
+				t.Errorf("%s=%q loads %d, want %d", tt.variable, tt.value, got, tt.want)
+			}
+		})
+	}
+}
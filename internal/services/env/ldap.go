@@ -0,0 +1,99 @@
+package envsvc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// LDAPTLSConfig returns the TLS configuration of ldaps:// and StartTLS connections. The
+// server name is left empty when not overridden, the host of the URL dialed applies.
+func (e Env) LDAPTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: e.LDAPServerName,
+	}
+
+	if e.LDAPCAPath != "" {
+		pem, err := os.ReadFile(e.LDAPCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LDAP CA bundle: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in LDAP CA bundle %s", e.LDAPCAPath)
+		}
+	}
+
+	if e.LDAPClientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(e.LDAPClientCertPath, e.LDAPClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load LDAP client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// LDAPURLs returns the LDAP server URLs in the order they are tried
+func (e Env) LDAPURLs() []string {
+	var urls []string
+	for u := range strings.SplitSeq(e.LDAPURL, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// LDAPAccountCheckList returns the account state checks
+func (e Env) LDAPAccountCheckList() []string {
+	var checks []string
+	for check := range strings.SplitSeq(e.LDAPAccountChecks, ",") {
+		if check = strings.TrimSpace(check); check != "" && !slices.Contains(checks, check) {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// validateLDAP checks the LDAP servers, their TLS settings and the account checks
+func (e Env) validateLDAP() error {
+	if !e.LDAPEnabled {
+		return nil
+	}
+	if e.LDAPSRVDomain != "" {
+		if e.LDAPStartTLS && e.LDAPSRVService != "ldap" {
+			return errors.New("LDAP_START_TLS requires the ldap LDAP_SRV_SERVICE")
+		}
+	} else {
+		urls := e.LDAPURLs()
+		if len(urls) == 0 {
+			return errors.New("LDAP_URL or LDAP_SRV_DOMAIN is required when LDAP is enabled")
+		}
+		for _, server := range urls {
+			u, err := url.Parse(server)
+			if err != nil {
+				return fmt.Errorf("invalid LDAP_URL %s: %w", server, err)
+			}
+			if e.LDAPStartTLS && u.Scheme != "ldap" {
+				return fmt.Errorf("LDAP_START_TLS requires ldap:// URLs, got %s", server)
+			}
+		}
+	}
+	for _, check := range e.LDAPAccountCheckList() {
+		switch check {
+		case AccountCheckActiveDirectory, AccountCheckNSAccountLock,
+			AccountCheckPPolicy, AccountCheckShadow:
+		default:
+			return fmt.Errorf("unknown LDAP_ACCOUNT_CHECKS check %q", check)
+		}
+	}
+	_, err := e.LDAPTLSConfig()
+	return err
+}
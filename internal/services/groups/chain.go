@@ -0,0 +1,63 @@
+package groupssvc
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// chainProvider combines several providers with union or first-match semantics.
+type chainProvider struct {
+	mode      string
+	providers []GroupProvider
+}
+
+// NewChainProvider combines providers, in order, with the given mode.
+func NewChainProvider(mode string, providers ...GroupProvider) GroupProvider {
+	return &chainProvider{
+		mode:      mode,
+		providers: providers,
+	}
+}
+
+func (c *chainProvider) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.Name())
+	}
+	return c.mode + "(" + strings.Join(names, ",") + ")"
+}
+
+// GetGroups queries the providers in order. ErrUserNotFound is only returned when no
+// provider knows the principal, any other error aborts the resolution.
+func (c *chainProvider) GetGroups(principal string) ([]string, error) {
+	found := false
+	seen := map[string]bool{}
+	groups := []string{}
+	for _, provider := range c.providers {
+		providerGroups, err := provider.GetGroups(principal)
+		if errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found = true
+		for _, group := range providerGroups {
+			if !seen[group] {
+				seen[group] = true
+				groups = append(groups, group)
+			}
+		}
+		if c.mode == ModeFirstMatch {
+			break
+		}
+	}
+
+	if !found {
+		return nil, ErrUserNotFound
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
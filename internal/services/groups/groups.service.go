@@ -0,0 +1,189 @@
+package groupssvc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	keytabsvc "github.com/froz42/kerbernetes/internal/services/keytab"
+	ldapsvc "github.com/froz42/kerbernetes/internal/services/ldap"
+	realmssvc "github.com/froz42/kerbernetes/internal/services/realms"
+	"github.com/samber/do"
+)
+
+const (
+	// ModeUnion merges the groups of every provider that knows the user
+	ModeUnion = "union"
+	// ModeFirstMatch uses the groups of the first provider that knows the user
+	ModeFirstMatch = "first-match"
+)
+
+// ErrUserNotFound is returned when a provider does not know the user
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrAccountDisabled is returned when the directory account of a user is disabled, locked
+// or expired
+var ErrAccountDisabled = errors.New("account is disabled")
+
+// GroupProvider resolves the groups of a user.
+type GroupProvider interface {
+	// Name identifies the provider in logs
+	Name() string
+	// GetGroups returns the group DNs of a principal, or ErrUserNotFound
+	GetGroups(principal string) ([]string, error)
+}
+
+// CachePurger is implemented by the providers caching users.
+type CachePurger interface {
+	// PurgeUser drops the cached entries of a principal, it reports whether any was cached
+	PurgeUser(principal string) bool
+}
+
+// AccountChecker is implemented by the providers knowing the account state of users.
+type AccountChecker interface {
+	// CheckAccount returns ErrAccountDisabled when the account of a principal is disabled,
+	// an unknown principal is left to GetGroups
+	CheckAccount(principal string) error
+}
+
+type GroupsSvc interface {
+	GroupProvider
+	CachePurger
+	AccountChecker
+	// Enabled reports whether at least one provider is configured
+	Enabled() bool
+}
+
+type groupsSvc struct {
+	providers []GroupProvider
+	chain     GroupProvider
+	logger    *slog.Logger
+}
+
+func NewProvider() func(i *do.Injector) (GroupsSvc, error) {
+	return func(i *do.Injector) (GroupsSvc, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[ldapsvc.LDAPSvc](i),
+			do.MustInvoke[realmssvc.RealmsSvc](i),
+			do.MustInvoke[keytabsvc.KeytabSvc](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+func New(
+	configService envsvc.EnvSvc,
+	ldapSvc ldapsvc.LDAPSvc,
+	realmsSvc realmssvc.RealmsSvc,
+	keytabSvc keytabsvc.KeytabSvc,
+	logger *slog.Logger,
+) (GroupsSvc, error) {
+	env := configService.GetEnv()
+	logger = logger.With("service", "groups")
+
+	names := providerNames(env)
+	providers := make([]GroupProvider, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "ldap":
+			if !env.LDAPEnabled {
+				return nil, fmt.Errorf("group provider ldap requires LDAP_ENABLED")
+			}
+			provider, err := NewLDAPProvider(
+				env,
+				ldapSvc,
+				realmsSvc,
+				keytabSvc.Keytab,
+				logger,
+			)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, provider)
+		case "static":
+			if env.StaticGroupsPath == "" {
+				return nil, fmt.Errorf("group provider static requires STATIC_GROUPS_PATH")
+			}
+			provider, err := NewStaticProvider(env.StaticGroupsPath, realmsSvc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load static groups: %w", err)
+			}
+			providers = append(providers, provider)
+		default:
+			return nil, fmt.Errorf("unknown group provider %q", name)
+		}
+	}
+
+	logger.Info("Group providers configured", "providers", names, "mode", env.GroupProvidersMode)
+	if len(env.LDAPAccountCheckList()) > 0 && !slices.Contains(names, "ldap") {
+		logger.Warn(
+			"Account checks skipped without the ldap group provider, disabled accounts keep " +
+				"getting credentials",
+		)
+	}
+	return &groupsSvc{
+		providers: providers,
+		chain:     NewChainProvider(env.GroupProvidersMode, providers...),
+		logger:    logger,
+	}, nil
+}
+
+// providerNames returns the configured provider names, LDAP alone when none are set
+// and LDAP is enabled.
+func providerNames(env envsvc.Env) []string {
+	names := []string{}
+	for name := range strings.SplitSeq(env.GroupProviders, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 && env.LDAPEnabled {
+		names = append(names, "ldap")
+	}
+	return names
+}
+
+func (s *groupsSvc) Name() string {
+	return s.chain.Name()
+}
+
+// GetGroups returns the group DNs of a principal from the configured providers.
+func (s *groupsSvc) GetGroups(principal string) ([]string, error) {
+	return s.chain.GetGroups(principal)
+}
+
+// Enabled reports whether at least one provider is configured.
+func (s *groupsSvc) Enabled() bool {
+	return len(s.providers) > 0
+}
+
+// PurgeUser drops the entries of a principal cached by the providers.
+func (s *groupsSvc) PurgeUser(principal string) bool {
+	purged := false
+	for _, provider := range s.providers {
+		if purger, ok := provider.(CachePurger); ok && purger.PurgeUser(principal) {
+			purged = true
+		}
+	}
+	if purged {
+		s.logger.Info("Purged cached user", "principal", principal)
+	}
+	return purged
+}
+
+// CheckAccount checks the account state of a principal with every provider knowing it.
+func (s *groupsSvc) CheckAccount(principal string) error {
+	for _, provider := range s.providers {
+		if checker, ok := provider.(AccountChecker); ok {
+			if err := checker.CheckAccount(principal); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
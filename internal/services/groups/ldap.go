@@ -0,0 +1,130 @@
+package groupssvc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/froz42/kerbernetes/internal/security"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	ldapsvc "github.com/froz42/kerbernetes/internal/services/ldap"
+	realmssvc "github.com/froz42/kerbernetes/internal/services/realms"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+)
+
+// ldapProvider resolves groups with the configured LDAP group filter, using the LDAP
+// settings of the principal realm when it overrides them. Users of other realms are only
+// looked up in the default directory when their realm shares it.
+type ldapProvider struct {
+	ldapSvc   ldapsvc.LDAPSvc
+	realms    map[string]ldapsvc.LDAPSvc
+	realmsSvc realmssvc.RealmsSvc
+	// checkAccounts is set when account state checks are configured
+	checkAccounts bool
+}
+
+// NewLDAPProvider returns a provider resolving groups from LDAP.
+func NewLDAPProvider(
+	env envsvc.Env,
+	ldapSvc ldapsvc.LDAPSvc,
+	realmsSvc realmssvc.RealmsSvc,
+	getKeytab func() *keytab.Keytab,
+	logger *slog.Logger,
+) (GroupProvider, error) {
+	p := &ldapProvider{
+		ldapSvc:       ldapSvc,
+		realms:        map[string]ldapsvc.LDAPSvc{},
+		realmsSvc:     realmsSvc,
+		checkAccounts: len(env.LDAPAccountCheckList()) > 0,
+	}
+	for realm, settings := range realmsSvc.LDAPRealms() {
+		realmSvc, err := ldapsvc.New(
+			settings.Apply(env),
+			getKeytab,
+			logger.With("realm", realm),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create LDAP client of realm %s: %w", realm, err)
+		}
+		p.realms[realm] = realmSvc
+	}
+	return p, nil
+}
+
+func (p *ldapProvider) Name() string {
+	return "ldap"
+}
+
+func (p *ldapProvider) GetGroups(principal string) ([]string, error) {
+	username, ldapSvc, err := p.service(principal)
+	if err != nil {
+		return nil, err
+	}
+	user, err := ldapSvc.GetUser(username)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user from LDAP: %w", err)
+	}
+	groups, err := ldapSvc.GetUserGroups(user.DN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user groups from LDAP: %w", err)
+	}
+	return groups, nil
+}
+
+// PurgeUser drops the cached LDAP entries of a principal.
+func (p *ldapProvider) PurgeUser(principal string) bool {
+	username, ldapSvc, err := p.service(principal)
+	if err != nil {
+		return false
+	}
+	return ldapSvc.PurgeUser(username)
+}
+
+// CheckAccount rejects principals whose LDAP account is disabled, locked or expired. The
+// account state is read from the directory, an account disabled after its user was cached
+// is refused at once.
+func (p *ldapProvider) CheckAccount(principal string) error {
+	if !p.checkAccounts {
+		return nil
+	}
+	username, ldapSvc, err := p.service(principal)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	user, err := ldapSvc.RefreshUser(username)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user from LDAP: %w", err)
+	}
+	if reason := ldapSvc.DisabledReason(user); reason != "" {
+		return fmt.Errorf("%w: %s", ErrAccountDisabled, reason)
+	}
+	return nil
+}
+
+// service returns the username of a principal and the LDAP client of its realm, or
+// ErrUserNotFound for a realm without LDAP settings that does not share the default
+// directory
+func (p *ldapProvider) service(principal string) (string, ldapsvc.LDAPSvc, error) {
+	username, realm := security.SplitPrincipal(principal)
+	if ldapSvc, ok := p.realms[realm]; ok {
+		return username, ldapSvc, nil
+	}
+	shared, err := p.realmsSvc.SharesDefaultDirectory(realm)
+	if err != nil {
+		return "", nil, err
+	}
+	if !shared {
+		return "", nil, ErrUserNotFound
+	}
+	return username, p.ldapSvc, nil
+}
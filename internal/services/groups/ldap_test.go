@@ -0,0 +1,100 @@
+package groupssvc
+
+import (
+	"errors"
+	"testing"
+
+	ldapsvc "github.com/froz42/kerbernetes/internal/services/ldap"
+	realmssvc "github.com/froz42/kerbernetes/internal/services/realms"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeDirectory caches the user entry it serves like the LDAP client
+type fakeDirectory struct {
+	ldapsvc.LDAPSvc
+	disabled bool
+	cached   *ldap.Entry
+}
+
+func (d *fakeDirectory) read() *ldap.Entry {
+	state := "FALSE"
+	if d.disabled {
+		state = "TRUE"
+	}
+	return ldap.NewEntry("uid=alice,dc=example,dc=com", map[string][]string{
+		"nsAccountLock": {state},
+	})
+}
+
+func (d *fakeDirectory) GetUser(username string) (*ldap.Entry, error) {
+	if d.cached == nil {
+		d.cached = d.read()
+	}
+	return d.cached, nil
+}
+
+func (d *fakeDirectory) RefreshUser(username string) (*ldap.Entry, error) {
+	d.cached = d.read()
+	return d.cached, nil
+}
+
+func (d *fakeDirectory) GetUserGroups(dn string) ([]string, error) {
+	return []string{"cn=developers,dc=example,dc=com"}, nil
+}
+
+func (d *fakeDirectory) DisabledReason(user *ldap.Entry) string {
+	if user.GetAttributeValue("nsAccountLock") == "TRUE" {
+		return "nsAccountLock: account is locked"
+	}
+	return ""
+}
+
+// sharedRealms shares the default directory with every realm
+type sharedRealms struct {
+	realmssvc.RealmsSvc
+}
+
+func (sharedRealms) SharesDefaultDirectory(realm string) (bool, error) {
+	return true, nil
+}
+
+func TestLDAPProviderCheckAccount(t *testing.T) {
+	tests := []struct {
+		name          string
+		checkAccounts bool
+		disabled      bool
+		want          error
+	}{
+		{name: "active account", checkAccounts: true},
+		{
+			name:          "account disabled after its user was cached",
+			checkAccounts: true,
+			disabled:      true,
+			want:          ErrAccountDisabled,
+		},
+		{name: "checks not configured", disabled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := &fakeDirectory{}
+			p := &ldapProvider{
+				ldapSvc:       directory,
+				realms:        map[string]ldapsvc.LDAPSvc{},
+				realmsSvc:     sharedRealms{},
+				checkAccounts: tt.checkAccounts,
+			}
+
+			// a login caches the user while the account is active
+			if _, err := p.GetGroups("alice@EXAMPLE.COM"); err != nil {
+				t.Fatalf("GetGroups: %v", err)
+			}
+			directory.disabled = tt.disabled
+
+			err := p.CheckAccount("alice@EXAMPLE.COM")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CheckAccount error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package groupssvc
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/froz42/kerbernetes/internal/security"
+	realmssvc "github.com/froz42/kerbernetes/internal/services/realms"
+	"sigs.k8s.io/yaml"
+)
+
+// staticProvider resolves groups from a YAML or JSON file mapping principals or usernames
+// to group DNs. The file is reloaded when it changes so that it can be a mounted ConfigMap.
+type staticProvider struct {
+	path      string
+	realmsSvc realmssvc.RealmsSvc
+
+	mu      sync.Mutex
+	modTime time.Time
+	groups  map[string][]string
+}
+
+// NewStaticProvider returns a provider resolving groups from the file at path.
+func NewStaticProvider(path string, realmsSvc realmssvc.RealmsSvc) (GroupProvider, error) {
+	p := &staticProvider{path: path, realmsSvc: realmsSvc}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *staticProvider) Name() string {
+	return "static"
+}
+
+// GetGroups returns the groups listed for the principal, or for its username when the
+// principal is not listed and its realm shares the default directory. Users absent from
+// the file are not found.
+func (p *staticProvider) GetGroups(principal string) ([]string, error) {
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	groups, ok := p.groups[principal]
+	p.mu.Unlock()
+	if ok {
+		return append([]string{}, groups...), nil
+	}
+
+	username, realm := security.SplitPrincipal(principal)
+	shared, err := p.realmsSvc.SharesDefaultDirectory(realm)
+	if err != nil {
+		return nil, err
+	}
+	if !shared {
+		return nil, ErrUserNotFound
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	groups, ok = p.groups[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return append([]string{}, groups...), nil
+}
+
+// reload reads the file again if its modification time changed
+func (p *staticProvider) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat static groups file: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.groups != nil && info.ModTime().Equal(p.modTime) {
+		return nil
+	}
+
+	raw, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read static groups file: %w", err)
+	}
+	groups := map[string][]string{}
+	if err := yaml.Unmarshal(raw, &groups); err != nil {
+		return fmt.Errorf("failed to parse static groups file: %w", err)
+	}
+	p.groups = groups
+	p.modTime = info.ModTime()
+	return nil
+}
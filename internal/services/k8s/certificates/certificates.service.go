@@ -0,0 +1,231 @@
+package certificatessvc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/froz42/kerbernetes/internal/security"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	"github.com/samber/do"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	managedLabel = "kerbernetes.io/managed"
+
+	pollInterval = 500 * time.Millisecond
+	pollTimeout  = 30 * time.Second
+)
+
+// ErrReservedIdentity is returned when the principal would impersonate a system identity
+var ErrReservedIdentity = errors.New("principal maps to a reserved Kubernetes identity")
+
+// Certificate is a signed client certificate and its private key, both PEM encoded
+type Certificate struct {
+	CertificatePEM []byte
+	KeyPEM         []byte
+	NotAfter       time.Time
+}
+
+type CertificatesService interface {
+	// IssueClientCertificate signs a client certificate with the principal as common name
+	// and the groups as organizations through the CertificateSigningRequest API
+	IssueClientCertificate(
+		ctx context.Context,
+		principal string,
+		groups []string,
+	) (*Certificate, error)
+}
+
+type certificatesService struct {
+	env       envsvc.Env
+	clientset *kubernetes.Clientset
+	logger    *slog.Logger
+}
+
+func NewProvider() func(i *do.Injector) (CertificatesService, error) {
+	return func(i *do.Injector) (CertificatesService, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[k8ssvc.K8sService](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+func New(
+	envSvc envsvc.EnvSvc,
+	k8sSvc k8ssvc.K8sService,
+	logger *slog.Logger,
+) (CertificatesService, error) {
+	return &certificatesService{
+		env:       envSvc.GetEnv(),
+		clientset: k8sSvc.GetClientset(),
+		logger:    logger.With("service", "certificates"),
+	}, nil
+}
+
+// IssueClientCertificate creates a CertificateSigningRequest, approves it and waits for
+// the signer. The request is deleted once the certificate is retrieved.
+func (svc *certificatesService) IssueClientCertificate(
+	ctx context.Context,
+	principal string,
+	groups []string,
+) (*Certificate, error) {
+	if security.IsReservedIdentity(principal) {
+		return nil, ErrReservedIdentity
+	}
+
+	// a group such as system:masters would grant more than any LdapGroupBinding
+	organizations := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if security.IsReservedIdentity(group) {
+			svc.logger.Warn("Dropping reserved group", "principal", principal, "group", group)
+			continue
+		}
+		organizations = append(organizations, group)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+	request, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   principal,
+			Organization: organizations,
+		},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	csrs := svc.clientset.CertificatesV1().CertificateSigningRequests()
+	expirationSeconds := int32(svc.env.CertificateDuration)
+	csr, err := csrs.Create(ctx, &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "kerbernetes-",
+			Labels: map[string]string{
+				managedLabel: "true",
+			},
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request: pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE REQUEST",
+				Bytes: request,
+			}),
+			SignerName:        svc.env.CertificateSignerName,
+			ExpirationSeconds: &expirationSeconds,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageClientAuth,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		svc.logger.Error("Failed to create certificate signing request", "error", err)
+		return nil, err
+	}
+	name := csr.Name
+	defer func() {
+		// the request is useless once signed or failed, the caller may already be gone
+		err := csrs.Delete(context.WithoutCancel(ctx), name, metav1.DeleteOptions{})
+		if err != nil {
+			svc.logger.Warn(
+				"Failed to delete certificate signing request",
+				"name", name,
+				"error", err,
+			)
+		}
+	}()
+
+	csr.Status.Conditions = append(
+		csr.Status.Conditions,
+		certificatesv1.CertificateSigningRequestCondition{
+			Type:           certificatesv1.CertificateApproved,
+			Status:         corev1.ConditionTrue,
+			Reason:         "KerberosAuthenticated",
+			Message:        "Approved by kerbernetes for " + principal,
+			LastUpdateTime: metav1.Now(),
+		},
+	)
+	_, err = csrs.UpdateApproval(ctx, name, csr, metav1.UpdateOptions{})
+	if err != nil {
+		svc.logger.Error("Failed to approve certificate signing request", "error", err)
+		return nil, err
+	}
+
+	var certificatePEM []byte
+	err = wait.PollUntilContextTimeout(
+		ctx,
+		pollInterval,
+		pollTimeout,
+		true,
+		func(ctx context.Context) (bool, error) {
+			current, err := csrs.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			for _, condition := range current.Status.Conditions {
+				if condition.Type == certificatesv1.CertificateDenied ||
+					condition.Type == certificatesv1.CertificateFailed {
+					return false, fmt.Errorf(
+						"certificate signing request %s: %s",
+						condition.Type,
+						condition.Message,
+					)
+				}
+			}
+			certificatePEM = current.Status.Certificate
+			return len(certificatePEM) > 0, nil
+		},
+	)
+	if err != nil {
+		svc.logger.Error(
+			"Certificate signing request was not signed",
+			"name", name,
+			"signer", svc.env.CertificateSignerName,
+			"error", err,
+		)
+		return nil, fmt.Errorf("certificate was not signed: %w", err)
+	}
+
+	block, _ := pem.Decode(certificatePEM)
+	if block == nil {
+		return nil, fmt.Errorf("signer returned an invalid certificate")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("signer returned an invalid certificate: %w", err)
+	}
+
+	svc.logger.Info(
+		"Issued client certificate",
+		"principal", principal,
+		"groups", len(organizations),
+		"notAfter", certificate.NotAfter,
+	)
+	return &Certificate{
+		CertificatePEM: certificatePEM,
+		KeyPEM:         pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		NotAfter:       certificate.NotAfter,
+	}, nil
+}
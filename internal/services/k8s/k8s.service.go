@@ -61,6 +61,17 @@ func New(logger *slog.Logger, apiConfig envsvc.Env) (K8sService, error) {
 		}
 	}
 
+	return NewForConfig(logger, apiConfig, restConfig, namespace)
+}
+
+// NewForConfig creates a service for the cluster of a REST configuration, such as a cluster
+// registered with a KerbernetesCluster.
+func NewForConfig(
+	logger *slog.Logger,
+	apiConfig envsvc.Env,
+	restConfig *rest.Config,
+	namespace string,
+) (K8sService, error) {
 	clientset, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
 		return nil, err
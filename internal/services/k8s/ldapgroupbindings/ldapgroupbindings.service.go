@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 
 	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
@@ -12,12 +13,36 @@ import (
 	informers "github.com/froz42/kerbernetes/k8s/generated/informers/externalversions"
 	lcrbinformer "github.com/froz42/kerbernetes/k8s/generated/informers/externalversions/rbac.kerbernetes.io/v1"
 	"github.com/samber/do"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 )
 
+// Finalizer is set on every LdapGroupBinding so that the bindings it produced are
+// removed before the object is deleted
+const Finalizer = "rbac.kerbernetes.io/bindings-cleanup"
+
+// EventHandler is notified after the cache has been updated for an LdapGroupBinding.
+// oldBinding is nil on creation and newBinding is nil on deletion.
+type EventHandler func(oldBinding, newBinding *v1.LdapGroupBinding)
+
 type LdapGroupBindingService interface {
 	Start(ctx context.Context) error
 	GetBindings() []*v1.LdapGroupBinding
+	// GetBinding returns the cached LdapGroupBinding with the given name, or nil
+	GetBinding(name string) *v1.LdapGroupBinding
+	// HasSynced reports whether the informer cache has been fully populated
+	HasSynced() bool
+	// AddEventHandler registers a handler notified of every LdapGroupBinding change
+	AddEventHandler(handler EventHandler)
+
+	// AddFinalizer adds the cleanup finalizer to an LdapGroupBinding
+	AddFinalizer(ctx context.Context, name string) error
+	// RemoveFinalizer removes the cleanup finalizer from an LdapGroupBinding
+	RemoveFinalizer(ctx context.Context, name string) error
+	// UpdateStatus replaces the status of an LdapGroupBinding
+	UpdateStatus(ctx context.Context, name string, status v1.LdapGroupBindingStatus) error
 }
 
 type ldapGroupBindingService struct {
@@ -27,6 +52,9 @@ type ldapGroupBindingService struct {
 	cache   []*v1.LdapGroupBinding
 	cacheMu sync.RWMutex
 
+	handlers   []EventHandler
+	handlersMu sync.RWMutex
+
 	informerFactory informers.SharedInformerFactory
 	informer        lcrbinformer.LdapGroupBindingInformer
 	stopCh          chan struct{}
@@ -74,33 +102,67 @@ func (svc *ldapGroupBindingService) initInformer() error {
 			binding := obj.(*v1.LdapGroupBinding)
 			svc.logger.Info("LdapClusterRoleBinding added", "name", binding.Name)
 			svc.add(binding)
+			svc.notify(nil, binding)
 		},
-		UpdateFunc: func(_, newObj interface{}) {
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldBinding := oldObj.(*v1.LdapGroupBinding)
 			binding := newObj.(*v1.LdapGroupBinding)
 			svc.logger.Info("LdapClusterRoleBinding updated", "name", binding.Name)
 			svc.update(binding)
+			svc.notify(oldBinding, binding)
 		},
 		DeleteFunc: func(obj interface{}) {
-			binding := obj.(*v1.LdapGroupBinding)
+			// the final state may be unknown if the watch missed the deletion
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			binding, ok := obj.(*v1.LdapGroupBinding)
+			if !ok {
+				svc.logger.Warn("Received deletion of unexpected object", "object", obj)
+				return
+			}
 			svc.logger.Info("LdapClusterRoleBinding deleted", "name", binding.Name)
 			svc.delete(binding)
+			svc.notify(binding, nil)
 		},
 	})
 	return err
 }
 
+// AddEventHandler registers a handler notified of every LdapGroupBinding change.
+func (svc *ldapGroupBindingService) AddEventHandler(handler EventHandler) {
+	svc.handlersMu.Lock()
+	defer svc.handlersMu.Unlock()
+	svc.handlers = append(svc.handlers, handler)
+}
+
+func (svc *ldapGroupBindingService) notify(oldBinding, newBinding *v1.LdapGroupBinding) {
+	svc.handlersMu.RLock()
+	defer svc.handlersMu.RUnlock()
+	for _, handler := range svc.handlers {
+		handler(oldBinding, newBinding)
+	}
+}
+
 func (svc *ldapGroupBindingService) Start(ctx context.Context) error {
 	svc.logger.Info("Starting LdapClusterRoleBinding informer")
 
+	// closing on cancellation also stops a sync against an unreachable API server
+	go func() {
+		<-ctx.Done()
+		close(svc.stopCh)
+	}()
 	svc.informerFactory.Start(svc.stopCh)
 
 	if !cache.WaitForCacheSync(svc.stopCh, svc.informer.Informer().HasSynced) {
+		if ctx.Err() != nil {
+			return nil
+		}
 		return fmt.Errorf("failed to sync informer cache")
 	}
 
 	svc.logger.Info("LdapClusterRoleBinding informer started and synced")
 	<-ctx.Done()
-	close(svc.stopCh)
 	return nil
 }
 
@@ -164,3 +226,95 @@ func (svc *ldapGroupBindingService) GetBindings() []*v1.LdapGroupBinding {
 	svc.logger.Info("Returning cached LdapClusterRoleBindings", "count", len(svc.cache))
 	return svc.cache
 }
+
+// GetBinding returns the cached LdapGroupBinding with the given name, or nil.
+func (svc *ldapGroupBindingService) GetBinding(name string) *v1.LdapGroupBinding {
+	svc.cacheMu.RLock()
+	defer svc.cacheMu.RUnlock()
+	for _, b := range svc.cache {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// AddFinalizer adds the cleanup finalizer to an LdapGroupBinding.
+func (svc *ldapGroupBindingService) AddFinalizer(ctx context.Context, name string) error {
+	return svc.mutate(ctx, name, func(binding *v1.LdapGroupBinding) bool {
+		if slices.Contains(binding.Finalizers, Finalizer) {
+			return false
+		}
+		binding.Finalizers = append(binding.Finalizers, Finalizer)
+		return true
+	})
+}
+
+// RemoveFinalizer removes the cleanup finalizer from an LdapGroupBinding.
+func (svc *ldapGroupBindingService) RemoveFinalizer(ctx context.Context, name string) error {
+	return svc.mutate(ctx, name, func(binding *v1.LdapGroupBinding) bool {
+		if !slices.Contains(binding.Finalizers, Finalizer) {
+			return false
+		}
+		binding.Finalizers = slices.DeleteFunc(binding.Finalizers, func(f string) bool {
+			return f == Finalizer
+		})
+		return true
+	})
+}
+
+// UpdateStatus replaces the status of an LdapGroupBinding.
+func (svc *ldapGroupBindingService) UpdateStatus(
+	ctx context.Context,
+	name string,
+	status v1.LdapGroupBindingStatus,
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		binding, err := svc.clientSet.RbacKerbernetesV1().
+			LdapGroupBindings().
+			Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		binding.Status = status
+		_, err = svc.clientSet.RbacKerbernetesV1().
+			LdapGroupBindings().
+			UpdateStatus(ctx, binding, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// mutate applies fn to the latest version of an LdapGroupBinding and updates it if fn
+// reports a change, retrying on conflicts.
+func (svc *ldapGroupBindingService) mutate(
+	ctx context.Context,
+	name string,
+	fn func(binding *v1.LdapGroupBinding) bool,
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		binding, err := svc.clientSet.RbacKerbernetesV1().
+			LdapGroupBindings().
+			Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if !fn(binding) {
+			return nil
+		}
+		_, err = svc.clientSet.RbacKerbernetesV1().
+			LdapGroupBindings().
+			Update(ctx, binding, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// HasSynced reports whether the informer cache has been fully populated.
+func (svc *ldapGroupBindingService) HasSynced() bool {
+	return svc.informer.Informer().HasSynced()
+}
@@ -0,0 +1,135 @@
+package ldapgroupbindingssvc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	serviceaccountssvc "github.com/froz42/kerbernetes/internal/services/k8s/serviceaccounts"
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	"github.com/go-ldap/ldap/v3"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// reservedUsernameLength is the username length kept available when checking that
+// generated binding names fit in a Kubernetes object name. Service accounts outside the
+// kerbernetes namespace are qualified with their namespace.
+const reservedUsernameLength = validation.DNS1123LabelMaxLength*2 + 1
+
+// Validate checks an LdapGroupBinding for errors that would otherwise only show up
+// when a user logs in.
+func Validate(binding *v1.LdapGroupBinding) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	dnPath := specPath.Child("ldapGroupDN")
+	if strings.TrimSpace(binding.Spec.LdapGroupDN) == "" {
+		errs = append(errs, field.Required(dnPath, "must be a distinguished name"))
+	} else if _, err := ldap.ParseDN(binding.Spec.LdapGroupDN); err != nil {
+		errs = append(errs, field.Invalid(dnPath, binding.Spec.LdapGroupDN, err.Error()))
+	}
+
+	// length of a generated binding name without the role name
+	nameOverhead := len(serviceaccountssvc.GenBindingName(
+		strings.Repeat("x", reservedUsernameLength),
+		"",
+		binding.Name,
+	))
+	nameBudget := validation.DNS1123SubdomainMaxLength - nameOverhead
+	if nameBudget <= 0 {
+		errs = append(errs, field.TooLong(
+			field.NewPath("metadata", "name"),
+			binding.Name,
+			len(binding.Name)+nameBudget-1,
+		))
+	}
+
+	// namespaced items with the same role name generate the same RoleBinding
+	roleBindings := make(map[string]int)
+	for i, item := range binding.Spec.Bindings {
+		itemPath := specPath.Child("bindings").Index(i)
+
+		switch item.Kind {
+		case "ClusterRole":
+		case "Role":
+			if !item.IsNamespaced() {
+				errs = append(errs, field.Required(
+					itemPath.Child("namespace"),
+					"namespace, namespaces or namespaceSelector is required for kind Role",
+				))
+			}
+		default:
+			errs = append(errs, field.NotSupported(
+				itemPath.Child("kind"),
+				item.Kind,
+				[]string{"ClusterRole", "Role"},
+			))
+		}
+
+		if item.ApiGroup != rbacv1.GroupName {
+			errs = append(errs, field.Invalid(
+				itemPath.Child("apiGroup"),
+				item.ApiGroup,
+				fmt.Sprintf("must be %q for kind %s", rbacv1.GroupName, item.Kind),
+			))
+		}
+
+		if item.Name == "" {
+			errs = append(errs, field.Required(itemPath.Child("name"), "role name is required"))
+		}
+
+		for j, namespace := range item.Namespaces {
+			for _, msg := range validation.IsDNS1123Label(namespace) {
+				errs = append(
+					errs,
+					field.Invalid(itemPath.Child("namespaces").Index(j), namespace, msg),
+				)
+			}
+		}
+
+		if item.NamespaceSelector != nil {
+			_, err := metav1.LabelSelectorAsSelector(item.NamespaceSelector)
+			if err != nil {
+				errs = append(errs, field.Invalid(
+					itemPath.Child("namespaceSelector"),
+					item.NamespaceSelector,
+					err.Error(),
+				))
+			}
+		}
+
+		duplicate := false
+		for j := range i {
+			if reflect.DeepEqual(item, binding.Spec.Bindings[j]) {
+				errs = append(errs, field.Duplicate(itemPath, item))
+				duplicate = true
+				break
+			}
+		}
+
+		// selected namespaces are only known at runtime, explicit ones are checked here
+		if !duplicate {
+			for _, namespace := range item.ExplicitNamespaces() {
+				key := namespace + "/" + item.Name
+				if first, exists := roleBindings[key]; exists {
+					errs = append(errs, field.Invalid(
+						itemPath,
+						item,
+						fmt.Sprintf("generates the same RoleBinding as spec.bindings[%d]", first),
+					))
+					break
+				}
+				roleBindings[key] = i
+			}
+		}
+
+		if nameBudget > 0 && len(item.Name) > nameBudget {
+			errs = append(errs, field.TooLong(itemPath.Child("name"), item.Name, nameBudget))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,174 @@
+package ldapgroupbindingssvc
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidate(t *testing.T) {
+	clusterRole := v1.LdapGroupBindingItem{
+		Kind:     "ClusterRole",
+		Name:     "view",
+		ApiGroup: rbacv1.GroupName,
+	}
+	role := v1.LdapGroupBindingItem{
+		Kind:      "Role",
+		Name:      "deployer",
+		Namespace: "dev",
+		ApiGroup:  rbacv1.GroupName,
+	}
+	with := func(item v1.LdapGroupBindingItem, change func(*v1.LdapGroupBindingItem)) v1.LdapGroupBindingItem {
+		change(&item)
+		return item
+	}
+
+	tests := []struct {
+		name        string
+		bindingName string
+		groupDN     string
+		items       []v1.LdapGroupBindingItem
+		// want lists the errors as field: type
+		want []string
+	}{
+		{
+			name:  "valid",
+			items: []v1.LdapGroupBindingItem{clusterRole, role},
+		},
+		{
+			name:    "missing group DN",
+			groupDN: " ",
+			items:   []v1.LdapGroupBindingItem{clusterRole},
+			want:    []string{"spec.ldapGroupDN: Required value"},
+		},
+		{
+			name:    "invalid group DN",
+			groupDN: "developers",
+			items:   []v1.LdapGroupBindingItem{clusterRole},
+			want:    []string{"spec.ldapGroupDN: Invalid value"},
+		},
+		{
+			name:        "name leaving no room for the role name",
+			bindingName: strings.Repeat("a", 112),
+			items:       []v1.LdapGroupBindingItem{clusterRole},
+			want:        []string{"metadata.name: Too long"},
+		},
+		{
+			name:        "role name too long for the binding name",
+			bindingName: strings.Repeat("a", 100),
+			items: []v1.LdapGroupBindingItem{with(clusterRole, func(item *v1.LdapGroupBindingItem) {
+				item.Name = strings.Repeat("r", 13)
+			})},
+			want: []string{"spec.bindings[0].name: Too long"},
+		},
+		{
+			name: "role without namespace",
+			items: []v1.LdapGroupBindingItem{with(role, func(item *v1.LdapGroupBindingItem) {
+				item.Namespace = ""
+			})},
+			want: []string{"spec.bindings[0].namespace: Required value"},
+		},
+		{
+			name: "role with a namespace selector",
+			items: []v1.LdapGroupBindingItem{with(role, func(item *v1.LdapGroupBindingItem) {
+				item.Namespace = ""
+				item.NamespaceSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"team": "dev"},
+				}
+			})},
+		},
+		{
+			name: "unsupported kind",
+			items: []v1.LdapGroupBindingItem{with(clusterRole, func(item *v1.LdapGroupBindingItem) {
+				item.Kind = "Group"
+			})},
+			want: []string{"spec.bindings[0].kind: Unsupported value"},
+		},
+		{
+			name: "wrong API group",
+			items: []v1.LdapGroupBindingItem{with(clusterRole, func(item *v1.LdapGroupBindingItem) {
+				item.ApiGroup = "rbac"
+			})},
+			want: []string{"spec.bindings[0].apiGroup: Invalid value"},
+		},
+		{
+			name: "missing role name",
+			items: []v1.LdapGroupBindingItem{with(clusterRole, func(item *v1.LdapGroupBindingItem) {
+				item.Name = ""
+			})},
+			want: []string{"spec.bindings[0].name: Required value"},
+		},
+		{
+			name: "invalid namespace in the list",
+			items: []v1.LdapGroupBindingItem{with(role, func(item *v1.LdapGroupBindingItem) {
+				item.Namespaces = []string{"staging", "Prod"}
+			})},
+			want: []string{"spec.bindings[0].namespaces[1]: Invalid value"},
+		},
+		{
+			name: "invalid namespace selector",
+			items: []v1.LdapGroupBindingItem{with(clusterRole, func(item *v1.LdapGroupBindingItem) {
+				item.NamespaceSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "team", Operator: "Near"},
+					},
+				}
+			})},
+			want: []string{"spec.bindings[0].namespaceSelector: Invalid value"},
+		},
+		{
+			name:  "duplicate item",
+			items: []v1.LdapGroupBindingItem{role, clusterRole, role},
+			want:  []string{"spec.bindings[2]: Duplicate value"},
+		},
+		{
+			name: "items generating the same RoleBinding",
+			items: []v1.LdapGroupBindingItem{
+				role,
+				with(role, func(item *v1.LdapGroupBindingItem) {
+					item.Kind = "ClusterRole"
+					item.Namespace = "staging"
+					item.Namespaces = []string{"dev"}
+				}),
+			},
+			want: []string{"spec.bindings[1]: Invalid value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binding := &v1.LdapGroupBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "developers"},
+				Spec: v1.LdapGroupBindingSpec{
+					LdapGroupDN: "cn=developers,ou=groups,dc=example,dc=com",
+					Bindings:    tt.items,
+				},
+			}
+			if tt.bindingName != "" {
+				binding.Name = tt.bindingName
+			}
+			if tt.groupDN != "" {
+				binding.Spec.LdapGroupDN = tt.groupDN
+			}
+
+			got := errorFields(Validate(binding))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// errorFields returns the field and type of each error
+func errorFields(errs field.ErrorList) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field+": "+err.Type.String())
+	}
+	return fields
+}
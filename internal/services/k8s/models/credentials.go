@@ -2,13 +2,36 @@ package k8smodels
 
 import "time"
 
+const (
+	ExecCredentialV1      = "client.authentication.k8s.io/v1"
+	ExecCredentialV1Beta1 = "client.authentication.k8s.io/v1beta1"
+)
+
+// ExecCredentialVersions lists the supported ExecCredential API versions
+var ExecCredentialVersions = []string{ExecCredentialV1, ExecCredentialV1Beta1}
+
 type Credentials struct {
 	Kind       string  `json:"kind" description:"Kind of the the object, should be ExecCredential"`
-	ApiVersion string  `json:"apiVersion" description:"API version of the object, should be client.authentication.k8s.io/v1beta1"`
+	ApiVersion string  `json:"apiVersion" description:"API version of the object, client.authentication.k8s.io/v1 or v1beta1"`
 	Status     *Status `json:"status,omitempty" description:"Status of the credentials, contains the token to use for authentication"`
 }
 
 type Status struct {
-	Token               string    `json:"token" description:"The token to use for authentication"`
-	ExpirationTimestamp time.Time `json:"expirationTimestamp" description:"The expiration timestamp of the token, if available"`
+	Token                 string    `json:"token,omitempty" description:"The token to use for authentication"`
+	ClientCertificateData string    `json:"clientCertificateData,omitempty" description:"PEM encoded client certificate to use for authentication"`
+	ClientKeyData         string    `json:"clientKeyData,omitempty" description:"PEM encoded private key of the client certificate"`
+	ExpirationTimestamp   time.Time `json:"expirationTimestamp" description:"The expiration timestamp of the token, if available"`
+}
+
+// ExecCredentialRequest is the ExecCredential kubectl passes to credential plugins in KUBERNETES_EXEC_INFO
+type ExecCredentialRequest struct {
+	_          struct{}            `additionalProperties:"true"`
+	Kind       string              `json:"kind,omitempty" description:"Kind of the the object, should be ExecCredential"`
+	ApiVersion string              `json:"apiVersion" enum:"client.authentication.k8s.io/v1,client.authentication.k8s.io/v1beta1" description:"API version of the credentials to return"`
+	Spec       *ExecCredentialSpec `json:"spec,omitempty" description:"Information about the request, ignored"`
+}
+
+type ExecCredentialSpec struct {
+	_           struct{} `additionalProperties:"true"`
+	Interactive bool     `json:"interactive,omitempty" description:"Whether the plugin can prompt the user"`
 }
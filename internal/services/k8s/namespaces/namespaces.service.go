@@ -0,0 +1,168 @@
+package namespacessvc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	"github.com/samber/do"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// EventHandler is notified of every Namespace change.
+// oldNamespace is nil on creation and newNamespace is nil on deletion.
+type EventHandler func(oldNamespace, newNamespace *corev1.Namespace)
+
+type NamespacesService interface {
+	Start(ctx context.Context) error
+	// HasSynced reports whether the informer cache has been fully populated
+	HasSynced() bool
+	// Exists reports whether a namespace exists in the cache
+	Exists(name string) (bool, error)
+	// List returns the names of the cached namespaces matching the selector
+	List(selector labels.Selector) ([]string, error)
+	// AddEventHandler registers a handler notified of every Namespace change
+	AddEventHandler(handler EventHandler)
+}
+
+type namespacesService struct {
+	logger *slog.Logger
+
+	handlers   []EventHandler
+	handlersMu sync.RWMutex
+
+	informerFactory informers.SharedInformerFactory
+	informer        corev1informers.NamespaceInformer
+	stopCh          chan struct{}
+}
+
+func NewProvider() func(i *do.Injector) (NamespacesService, error) {
+	return func(i *do.Injector) (NamespacesService, error) {
+		return New(
+			do.MustInvoke[*slog.Logger](i),
+			do.MustInvoke[k8ssvc.K8sService](i),
+		)
+	}
+}
+
+func New(logger *slog.Logger, k8sSvc k8ssvc.K8sService) (NamespacesService, error) {
+	informerFactory := informers.NewSharedInformerFactory(k8sSvc.GetClientset(), 0)
+
+	svc := &namespacesService{
+		logger:          logger.With("service", "namespaces"),
+		informerFactory: informerFactory,
+		informer:        informerFactory.Core().V1().Namespaces(),
+		stopCh:          make(chan struct{}),
+	}
+
+	err := svc.initInformer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize informer: %w", err)
+	}
+
+	return svc, nil
+}
+
+// initInformer sets up the informer with handlers
+func (svc *namespacesService) initInformer() error {
+	_, err := svc.informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			svc.notify(nil, obj.(*corev1.Namespace))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			svc.notify(oldObj.(*corev1.Namespace), newObj.(*corev1.Namespace))
+		},
+		DeleteFunc: func(obj interface{}) {
+			// the final state may be unknown if the watch missed the deletion
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			namespace, ok := obj.(*corev1.Namespace)
+			if !ok {
+				svc.logger.Warn("Received deletion of unexpected object", "object", obj)
+				return
+			}
+			svc.notify(namespace, nil)
+		},
+	})
+	return err
+}
+
+func (svc *namespacesService) Start(ctx context.Context) error {
+	svc.logger.Info("Starting Namespace informer")
+
+	// closing on cancellation also stops a sync against an unreachable API server
+	go func() {
+		<-ctx.Done()
+		close(svc.stopCh)
+	}()
+	svc.informerFactory.Start(svc.stopCh)
+
+	if !cache.WaitForCacheSync(svc.stopCh, svc.informer.Informer().HasSynced) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to sync informer cache")
+	}
+
+	svc.logger.Info("Namespace informer started and synced")
+	<-ctx.Done()
+	return nil
+}
+
+// HasSynced reports whether the informer cache has been fully populated.
+func (svc *namespacesService) HasSynced() bool {
+	return svc.informer.Informer().HasSynced()
+}
+
+// Exists reports whether a namespace exists in the cache.
+func (svc *namespacesService) Exists(name string) (bool, error) {
+	_, err := svc.informer.Lister().Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// List returns the names of the cached namespaces matching the selector.
+func (svc *namespacesService) List(selector labels.Selector) ([]string, error) {
+	namespaces, err := svc.informer.Lister().List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		// terminating namespaces reject new objects
+		if namespace.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+		names = append(names, namespace.Name)
+	}
+	return names, nil
+}
+
+// AddEventHandler registers a handler notified of every Namespace change.
+func (svc *namespacesService) AddEventHandler(handler EventHandler) {
+	svc.handlersMu.Lock()
+	defer svc.handlersMu.Unlock()
+	svc.handlers = append(svc.handlers, handler)
+}
+
+func (svc *namespacesService) notify(oldNamespace, newNamespace *corev1.Namespace) {
+	svc.handlersMu.RLock()
+	defer svc.handlersMu.RUnlock()
+	for _, handler := range svc.handlers {
+		handler(oldNamespace, newNamespace)
+	}
+}
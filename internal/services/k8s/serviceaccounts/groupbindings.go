@@ -0,0 +1,196 @@
+package serviceaccountssvc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GroupBindingName returns the name of the bindings granting a role of an LdapGroupBinding
+// to its group. Service account names can not contain colons so they never collide.
+func GroupBindingName(roleName string, ldapGroupBindingName string) string {
+	return fmt.Sprintf("kerbernetes-group:%s:%s", ldapGroupBindingName, roleName)
+}
+
+// IsGroupBinding reports whether a managed binding grants a Group subject.
+func IsGroupBinding(subjects []rbacv1.Subject) bool {
+	for _, subject := range subjects {
+		if subject.Kind == rbacv1.GroupKind {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyGroupClusterRoleBinding creates or updates the cluster role binding granting a
+// cluster role to a group.
+func (svc *serviceAccountsService) ApplyGroupClusterRoleBinding(
+	ctx context.Context,
+	group string,
+	clusterRoleName string,
+	ldapGroupBindingName string,
+) (*rbacv1.ClusterRoleBinding, error) {
+	clusterRoleBindings := svc.clientset.RbacV1().ClusterRoleBindings()
+	desired := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: groupBindingMeta(
+			GroupBindingName(clusterRoleName, ldapGroupBindingName),
+			"",
+			ldapGroupBindingName,
+		),
+		Subjects: groupSubjects(group),
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     clusterRoleName,
+		},
+	}
+
+	existing, err := clusterRoleBindings.Get(ctx, desired.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		binding, err := clusterRoleBindings.Create(ctx, desired, metav1.CreateOptions{})
+		if err != nil {
+			svc.logger.Error("Failed to create group cluster role binding", "error", err)
+			return nil, err
+		}
+		svc.logger.Info("Created group cluster role binding", "name", binding.Name)
+		return binding, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if reflect.DeepEqual(existing.Subjects, desired.Subjects) &&
+		BindingSource(existing.ObjectMeta) == ldapGroupBindingName {
+		return existing, nil
+	}
+	existing.Subjects = desired.Subjects
+	setAnnotation(&existing.ObjectMeta, ldapGroupBindingAnnotation, ldapGroupBindingName)
+	binding, err := clusterRoleBindings.Update(ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		svc.logger.Error("Failed to update group cluster role binding", "error", err)
+		return nil, err
+	}
+	svc.logger.Info("Updated group cluster role binding", "name", binding.Name)
+	return binding, nil
+}
+
+// ApplyGroupRoleBinding creates or updates the role binding granting a role to a group in a
+// namespace. The binding is recreated when its role reference changes.
+func (svc *serviceAccountsService) ApplyGroupRoleBinding(
+	ctx context.Context,
+	namespace string,
+	group string,
+	roleRef rbacv1.RoleRef,
+	ldapGroupBindingName string,
+) (*rbacv1.RoleBinding, error) {
+	roleBindings := svc.clientset.RbacV1().RoleBindings(namespace)
+	desired := &rbacv1.RoleBinding{
+		ObjectMeta: groupBindingMeta(
+			GroupBindingName(roleRef.Name, ldapGroupBindingName),
+			namespace,
+			ldapGroupBindingName,
+		),
+		Subjects: groupSubjects(group),
+		RoleRef:  roleRef,
+	}
+
+	existing, err := roleBindings.Get(ctx, desired.Name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		if existing.RoleRef == roleRef {
+			if reflect.DeepEqual(existing.Subjects, desired.Subjects) &&
+				BindingSource(existing.ObjectMeta) == ldapGroupBindingName {
+				return existing, nil
+			}
+			existing.Subjects = desired.Subjects
+			setAnnotation(&existing.ObjectMeta, ldapGroupBindingAnnotation, ldapGroupBindingName)
+			binding, err := roleBindings.Update(ctx, existing, metav1.UpdateOptions{})
+			if err != nil {
+				svc.logger.Error("Failed to update group role binding", "error", err)
+				return nil, err
+			}
+			svc.logger.Info(
+				"Updated group role binding",
+				"name", binding.Name,
+				"namespace", namespace,
+			)
+			return binding, nil
+		}
+
+		// roleRef is immutable, switching between Role and ClusterRole needs a new binding
+		err = roleBindings.Delete(ctx, existing.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			svc.logger.Error("Failed to delete group role binding", "error", err)
+			return nil, err
+		}
+	}
+
+	binding, err := roleBindings.Create(ctx, desired, metav1.CreateOptions{})
+	if err != nil {
+		svc.logger.Error("Failed to create group role binding", "error", err)
+		return nil, err
+	}
+	svc.logger.Info("Created group role binding", "name", binding.Name, "namespace", namespace)
+	return binding, nil
+}
+
+// GetGroupRoleBindings retrieves the managed role bindings granted to any of the groups.
+func (svc *serviceAccountsService) GetGroupRoleBindings(
+	ctx context.Context,
+	groups []string,
+) ([]rbacv1.RoleBinding, error) {
+	bindings, err := svc.clientset.RbacV1().
+		RoleBindings("").
+		List(ctx, metav1.ListOptions{
+			LabelSelector: saManagedLabel + "=true",
+		})
+	if err != nil {
+		svc.logger.Error("Failed to get role bindings", "error", err)
+		return nil, err
+	}
+
+	var filteredBindings []rbacv1.RoleBinding
+	for _, binding := range bindings.Items {
+		for _, subject := range binding.Subjects {
+			if subject.Kind == rbacv1.GroupKind && slices.Contains(groups, subject.Name) {
+				filteredBindings = append(filteredBindings, binding)
+				break
+			}
+		}
+	}
+	return filteredBindings, nil
+}
+
+func groupBindingMeta(
+	name string,
+	namespace string,
+	ldapGroupBindingName string,
+) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels: map[string]string{
+			saManagedLabel: "true",
+		},
+		Annotations: map[string]string{
+			ldapGroupBindingAnnotation: ldapGroupBindingName,
+		},
+	}
+}
+
+func groupSubjects(group string) []rbacv1.Subject {
+	return []rbacv1.Subject{
+		{
+			Kind:     rbacv1.GroupKind,
+			APIGroup: rbacv1.GroupName,
+			Name:     group,
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package serviceaccountssvc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/froz42/kerbernetes/internal/security"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// maxNameLength keeps generated binding names within the length reserved for the
+	// username when validating LdapGroupBindings
+	maxNameLength = 63
+	// hashLength is the number of hexadecimal characters of the collision suffix
+	hashLength = 8
+)
+
+// MapPrincipal returns the service account name of a Kerberos principal.
+// The realm is stripped unless keepRealm is set, the prefix is prepended, the name is
+// lowercased and every character that is not valid in a DNS-1123 subdomain is replaced
+// with a dash. Names that are empty or too long once sanitised get a hash suffix.
+func MapPrincipal(principal string, prefix string, keepRealm bool) string {
+	name := principal
+	if !keepRealm {
+		name, _ = security.SplitPrincipal(principal)
+	}
+	name = prefix + name
+
+	labels := []string{}
+	for label := range strings.SplitSeq(strings.ToLower(name), ".") {
+		label = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+				return r
+			}
+			return '-'
+		}, label)
+		label = strings.Trim(label, "-")
+		if label != "" {
+			labels = append(labels, label)
+		}
+	}
+	name = strings.Join(labels, ".")
+
+	if name == "" || len(name) > maxNameLength {
+		return HashedName(name, principal)
+	}
+	return name
+}
+
+// HashedName returns name suffixed with a hash of the principal, truncating name so
+// that the result fits in maxNameLength.
+func HashedName(name string, principal string) string {
+	sum := sha256.Sum256([]byte(principal))
+	suffix := hex.EncodeToString(sum[:])[:hashLength]
+
+	if name == "" {
+		name = "user"
+	}
+	if len(name) > maxNameLength-hashLength-1 {
+		name = name[:maxNameLength-hashLength-1]
+	}
+	name = strings.TrimRight(name, "-.")
+	return name + "-" + suffix
+}
+
+// Principal returns the Kerberos principal a service account was created for.
+// Service accounts created before the annotation existed are named after the username.
+func Principal(sa *corev1.ServiceAccount) string {
+	if principal, ok := sa.Annotations[principalAnnotation]; ok {
+		return principal
+	}
+	return sa.Name
+}
+
+// Username returns the username, without realm, a service account was created for.
+func Username(sa *corev1.ServiceAccount) string {
+	username, _ := security.SplitPrincipal(Principal(sa))
+	return username
+}
+
+// Ref returns the namespaced name of a service account.
+func Ref(sa *corev1.ServiceAccount) types.NamespacedName {
+	return types.NamespacedName{Namespace: sa.Namespace, Name: sa.Name}
+}
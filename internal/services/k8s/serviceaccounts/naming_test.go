@@ -0,0 +1,109 @@
+package serviceaccountssvc
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestMapPrincipal(t *testing.T) {
+	long := strings.Repeat("a", 70)
+
+	tests := []struct {
+		name      string
+		principal string
+		prefix    string
+		keepRealm bool
+		want      string
+	}{
+		{name: "realm is stripped", principal: "alice@EXAMPLE.COM", want: "alice"},
+		{
+			name:      "realm is kept",
+			principal: "alice@EXAMPLE.COM",
+			keepRealm: true,
+			want:      "alice-example.com",
+		},
+		{name: "prefix", principal: "alice@EXAMPLE.COM", prefix: "k-", want: "k-alice"},
+		{name: "lowercased", principal: "Alice.Smith@EXAMPLE.COM", want: "alice.smith"},
+		{
+			name:      "invalid characters are replaced",
+			principal: "host/web01.example.com@EXAMPLE.COM",
+			want:      "host-web01.example.com",
+		},
+		{name: "empty labels are dropped", principal: "alice..smith.", want: "alice.smith"},
+		{name: "empty name is hashed", principal: "@EXAMPLE.COM", want: "user-3a5240cc"},
+		{
+			name:      "name without valid characters is hashed",
+			principal: "___@EXAMPLE.COM",
+			want:      "user-a61b3b44",
+		},
+		{
+			name:      "long name is truncated and hashed",
+			principal: long + "@EXAMPLE.COM",
+			want:      long[:maxNameLength-hashLength-1] + "-f02d135e",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MapPrincipal(tt.principal, tt.prefix, tt.keepRealm)
+			if got != tt.want {
+				t.Errorf("MapPrincipal(%q) = %q, want %q", tt.principal, got, tt.want)
+			}
+			if errs := validation.IsDNS1123Subdomain(got); len(errs) > 0 {
+				t.Errorf("MapPrincipal(%q) = %q is not a valid name: %v", tt.principal, got, errs)
+			}
+			if len(got) > maxNameLength {
+				t.Errorf("MapPrincipal(%q) is %d characters long", tt.principal, len(got))
+			}
+		})
+	}
+}
+
+func TestMapPrincipalCollisions(t *testing.T) {
+	// principals sanitised to the same long name keep distinct names
+	a := MapPrincipal(strings.Repeat("a", 70)+"@EXAMPLE.COM", "", false)
+	b := MapPrincipal(strings.Repeat("a", 70)+"@OTHER.COM", "", false)
+	if a == b {
+		t.Errorf("principals of different realms both map to %q", a)
+	}
+}
+
+func TestHashedName(t *testing.T) {
+	truncated := strings.Repeat("a", maxNameLength-hashLength-1)
+
+	tests := []struct {
+		name      string
+		input     string
+		principal string
+		want      string
+	}{
+		{name: "empty name", input: "", principal: "x@R", want: "user-3ab8c737"},
+		{name: "short name", input: "alice", principal: "x@R", want: "alice-3ab8c737"},
+		{
+			name:      "long name is truncated",
+			input:     truncated + "bcd",
+			principal: "x@R",
+			want:      truncated + "-3ab8c737",
+		},
+		{
+			name:      "trailing separators are trimmed",
+			input:     truncated[:50] + "-.",
+			principal: "x@R",
+			want:      truncated[:50] + "-3ab8c737",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HashedName(tt.input, tt.principal)
+			if got != tt.want {
+				t.Errorf("HashedName(%q, %q) = %q, want %q", tt.input, tt.principal, got, tt.want)
+			}
+			if len(got) > maxNameLength {
+				t.Errorf("HashedName(%q) is %d characters long", tt.input, len(got))
+			}
+		})
+	}
+}
@@ -2,8 +2,12 @@ package serviceaccountssvc
 
 import (
 	"context"
+	"encoding/json"
+	goerrors "errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 
 	envsvc "github.com/froz42/kerbernetes/internal/services/env"
 	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
@@ -13,35 +17,68 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 )
 
-const saManagedLabel = "kerbernetes.io/managed"
+const (
+	saManagedLabel = "kerbernetes.io/managed"
+	// groupsAnnotation records the groups the service account was last reconciled with
+	groupsAnnotation = "kerbernetes.io/groups"
+	// ldapGroupBindingAnnotation records the LdapGroupBinding a binding was generated from
+	ldapGroupBindingAnnotation = "kerbernetes.io/ldapgroupbinding"
+	// principalAnnotation records the Kerberos principal a service account was created for
+	principalAnnotation = "kerbernetes.io/principal"
+)
+
+// errPrincipalCollision is returned when the mapped name belongs to another principal, or
+// to a service account kerbernetes does not manage
+var errPrincipalCollision = goerrors.New("service account belongs to another principal")
 
 type ServiceAccountsService interface {
-	// UpsertServiceAccount retrieves or creates a service account for the given username
-	UpsertServiceAccount(ctx context.Context, username string) (*corev1.ServiceAccount, error)
+	// UpsertServiceAccount retrieves or creates the service account of a principal in the
+	// given namespace, the kerbernetes namespace when empty. The name is mapped from the
+	// principal and prefix, every other method takes the resulting service account.
+	UpsertServiceAccount(
+		ctx context.Context,
+		principal string,
+		namespace string,
+		prefix string,
+	) (*corev1.ServiceAccount, error)
+
+	// ListServiceAccounts retrieves every service account managed by kerbernetes
+	ListServiceAccounts(ctx context.Context) ([]corev1.ServiceAccount, error)
+
+	// GetServiceAccount retrieves a service account
+	GetServiceAccount(
+		ctx context.Context,
+		account types.NamespacedName,
+	) (*corev1.ServiceAccount, error)
+
+	// SetGroups records the groups a service account was reconciled with
+	SetGroups(ctx context.Context, account types.NamespacedName, groups []string) error
 
 	// IssueToken creates a token for the service account
-	IssueToken(ctx context.Context, username string) (*authv1.TokenRequest, error)
+	IssueToken(ctx context.Context, account types.NamespacedName) (*authv1.TokenRequest, error)
 
 	// GetSAClusterRoleBindings get the cluster role bindings for an service account
 	GetClusterRoleBindings(
 		ctx context.Context,
-		username string,
+		account types.NamespacedName,
 	) ([]rbacv1.ClusterRoleBinding, error)
 
 	// CreateClusterRoleBinding creates a cluster role binding for the service account
 	CreateClusterRoleBinding(
 		ctx context.Context,
-		username string,
+		account types.NamespacedName,
 		clusterRoleName string,
 		ldapGroundBindingName string,
 	) (*rbacv1.ClusterRoleBinding, error)
 
 	UpdateClusterRoleBinding(
 		ctx context.Context,
-		username string,
+		account types.NamespacedName,
 		clusterRoleName string,
 		ldapGroundBindingName string,
 	) (*rbacv1.ClusterRoleBinding, error)
@@ -52,13 +89,13 @@ type ServiceAccountsService interface {
 	// GetRolesBindings retrieves the cluster role bindings for a service account
 	GetRoleBindings(
 		ctx context.Context,
-		username string,
+		account types.NamespacedName,
 	) ([]rbacv1.RoleBinding, error)
 
 	// CreateRoleBinding creates a role binding for the service account
 	CreateRoleBinding(
 		ctx context.Context,
-		username string,
+		account types.NamespacedName,
 		namespace string,
 		ldapGroundBindingName string,
 		roleRef rbacv1.RoleRef,
@@ -67,7 +104,7 @@ type ServiceAccountsService interface {
 	// UpdateRoleBinding updates an existing role binding for the service account
 	UpdateRoleBinding(
 		ctx context.Context,
-		username string,
+		account types.NamespacedName,
 		namespace string,
 		roleRef rbacv1.RoleRef,
 		ldapGroundBindingName string,
@@ -75,11 +112,44 @@ type ServiceAccountsService interface {
 
 	// DeleteRoleBinding deletes a role binding by its name
 	DeleteRoleBinding(ctx context.Context, namespace string, name string) error
+
+	// BindingName returns the name of the bindings generated for a service account
+	BindingName(
+		account types.NamespacedName,
+		roleName string,
+		ldapGroundBindingName string,
+	) string
+
+	// GetLdapGroupBindingBindings retrieves the bindings generated from an LdapGroupBinding
+	GetLdapGroupBindingBindings(
+		ctx context.Context,
+		ldapGroupBindingName string,
+	) ([]rbacv1.ClusterRoleBinding, []rbacv1.RoleBinding, error)
+
+	// ApplyGroupClusterRoleBinding creates or updates the cluster role binding of a group
+	ApplyGroupClusterRoleBinding(
+		ctx context.Context,
+		group string,
+		clusterRoleName string,
+		ldapGroupBindingName string,
+	) (*rbacv1.ClusterRoleBinding, error)
+
+	// ApplyGroupRoleBinding creates or updates the role binding of a group in a namespace
+	ApplyGroupRoleBinding(
+		ctx context.Context,
+		namespace string,
+		group string,
+		roleRef rbacv1.RoleRef,
+		ldapGroupBindingName string,
+	) (*rbacv1.RoleBinding, error)
+
+	// GetGroupRoleBindings retrieves the role bindings granted to any of the groups
+	GetGroupRoleBindings(ctx context.Context, groups []string) ([]rbacv1.RoleBinding, error)
 }
 
 type serviceAccountsService struct {
 	env       envsvc.Env
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
 	namespace string
 	logger    *slog.Logger
 }
@@ -107,33 +177,148 @@ func New(
 	}, nil
 }
 
-// UpsertServiceAccount retrieves or creates a service account for the given username.
+// UpsertServiceAccount retrieves or creates the service account of a principal.
+// The name is mapped from the principal, a hash suffix is added when the mapped name
+// already belongs to another principal.
 func (svc *serviceAccountsService) UpsertServiceAccount(
 	ctx context.Context,
-	username string,
+	principal string,
+	namespace string,
+	prefix string,
 ) (*corev1.ServiceAccount, error) {
-	sa, err := svc.clientset.CoreV1().
-		ServiceAccounts(svc.namespace).
-		Get(ctx, username, metav1.GetOptions{})
+	if namespace == "" {
+		namespace = svc.namespace
+	}
+	account := types.NamespacedName{
+		Namespace: namespace,
+		Name:      MapPrincipal(principal, prefix, svc.env.SANameKeepRealm),
+	}
+	sa, err := svc.upsertServiceAccount(ctx, account, principal)
+	if goerrors.Is(err, errPrincipalCollision) {
+		svc.logger.Warn(
+			"Service account name collision, using hashed name",
+			"principal", principal,
+			"serviceAccount", account,
+		)
+		account.Name = HashedName(account.Name, principal)
+		sa, err = svc.upsertServiceAccount(ctx, account, principal)
+	}
+	return sa, err
+}
+
+func (svc *serviceAccountsService) upsertServiceAccount(
+	ctx context.Context,
+	account types.NamespacedName,
+	principal string,
+) (*corev1.ServiceAccount, error) {
+	sa, err := svc.GetServiceAccount(ctx, account)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return svc.createServiceAccount(ctx, username)
+			return svc.createServiceAccount(ctx, account, principal)
 		}
 		svc.logger.Error("Failed to get service account", "error", err)
 		return nil, err
 	}
 
+	// a workload service account, such as default, must never be taken over
+	if sa.Labels[saManagedLabel] != "true" {
+		return nil, errPrincipalCollision
+	}
+	owner, annotated := sa.Annotations[principalAnnotation]
+	if annotated && owner != principal {
+		return nil, errPrincipalCollision
+	}
+
+	// service accounts created before the principal annotation existed are annotated on
+	// their next login
+	if !annotated {
+		setAnnotation(&sa.ObjectMeta, principalAnnotation, principal)
+		sa, err = svc.clientset.CoreV1().
+			ServiceAccounts(sa.Namespace).
+			Update(ctx, sa, metav1.UpdateOptions{})
+		if err != nil {
+			svc.logger.Error("Failed to annotate service account", "error", err)
+			return nil, err
+		}
+	}
+
 	svc.logger.Info("Found existing service account", "name", sa.Name, "namespace", sa.Namespace)
 	return sa, nil
 }
 
+// ListServiceAccounts retrieves every service account managed by kerbernetes.
+func (svc *serviceAccountsService) ListServiceAccounts(
+	ctx context.Context,
+) ([]corev1.ServiceAccount, error) {
+	// service accounts of realms with their own namespace live outside the kerbernetes one
+	serviceAccounts, err := svc.clientset.CoreV1().
+		ServiceAccounts("").
+		List(ctx, metav1.ListOptions{
+			LabelSelector: saManagedLabel + "=true",
+		})
+	if err != nil {
+		svc.logger.Error("Failed to list service accounts", "error", err)
+		return nil, err
+	}
+
+	svc.logger.Info("Retrieved service accounts", "count", len(serviceAccounts.Items))
+	return serviceAccounts.Items, nil
+}
+
+// GetServiceAccount retrieves a service account.
+func (svc *serviceAccountsService) GetServiceAccount(
+	ctx context.Context,
+	account types.NamespacedName,
+) (*corev1.ServiceAccount, error) {
+	return svc.clientset.CoreV1().
+		ServiceAccounts(account.Namespace).
+		Get(ctx, account.Name, metav1.GetOptions{})
+}
+
+// SetGroups records the groups a service account was reconciled with.
+// The groups are stored in an annotation so that reconciliation can be replayed without
+// querying LDAP when an LdapGroupBinding changes.
+func (svc *serviceAccountsService) SetGroups(
+	ctx context.Context,
+	account types.NamespacedName,
+	groups []string,
+) error {
+	sorted := slices.Clone(groups)
+	slices.Sort(sorted)
+	if sorted == nil {
+		sorted = []string{}
+	}
+	raw, err := json.Marshal(sorted)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		sa, err := svc.GetServiceAccount(ctx, account)
+		if err != nil {
+			return err
+		}
+		if sa.Annotations[groupsAnnotation] == string(raw) {
+			return nil
+		}
+		if sa.Annotations == nil {
+			sa.Annotations = map[string]string{}
+		}
+		sa.Annotations[groupsAnnotation] = string(raw)
+		_, err = svc.clientset.CoreV1().
+			ServiceAccounts(account.Namespace).
+			Update(ctx, sa, metav1.UpdateOptions{})
+		return err
+	})
+}
+
 // IssueToken creates a token for the service account.
 func (svc *serviceAccountsService) IssueToken(
 	ctx context.Context,
-	username string,
+	account types.NamespacedName,
 ) (*authv1.TokenRequest, error) {
-	token, err := svc.clientset.CoreV1().ServiceAccounts(svc.namespace).
-		CreateToken(ctx, username, &authv1.TokenRequest{
+	token, err := svc.clientset.CoreV1().ServiceAccounts(account.Namespace).
+		CreateToken(ctx, account.Name, &authv1.TokenRequest{
 			Spec: authv1.TokenRequestSpec{
 				Audiences:         []string{svc.env.TokenAudience},
 				ExpirationSeconds: int64Ptr(int64(svc.env.TokenDuration)),
@@ -147,9 +332,9 @@ func (svc *serviceAccountsService) IssueToken(
 	svc.logger.Info(
 		"Issued token for service account",
 		"name",
-		username,
+		account.Name,
 		"namespace",
-		svc.namespace,
+		account.Namespace,
 	)
 	return token, nil
 }
@@ -157,7 +342,7 @@ func (svc *serviceAccountsService) IssueToken(
 // GetClusterRoleBindings retrieves the cluster role bindings for a service account.
 func (svc *serviceAccountsService) GetClusterRoleBindings(
 	ctx context.Context,
-	username string,
+	account types.NamespacedName,
 ) ([]rbacv1.ClusterRoleBinding, error) {
 	bindings, err := svc.clientset.RbacV1().
 		ClusterRoleBindings().
@@ -172,19 +357,15 @@ func (svc *serviceAccountsService) GetClusterRoleBindings(
 	// filter bindings for the specific service account
 	var filteredBindings []rbacv1.ClusterRoleBinding
 	for _, binding := range bindings.Items {
-		for _, subject := range binding.Subjects {
-			if subject.Kind == "ServiceAccount" && subject.Name == username &&
-				subject.Namespace == svc.namespace {
-				filteredBindings = append(filteredBindings, binding)
-				break
-			}
+		if IsSubject(binding.Subjects, account) {
+			filteredBindings = append(filteredBindings, binding)
 		}
 	}
 
 	svc.logger.Info(
 		"Retrieved cluster role bindings",
-		"username",
-		username,
+		"serviceAccount",
+		account,
 		"count",
 		len(filteredBindings),
 	)
@@ -194,22 +375,25 @@ func (svc *serviceAccountsService) GetClusterRoleBindings(
 // CreateClusterRoleBinding creates a cluster role binding for the service account.
 func (svc *serviceAccountsService) CreateClusterRoleBinding(
 	ctx context.Context,
-	username string,
+	account types.NamespacedName,
 	roleName string,
 	ldapGroundBindingName string,
 ) (*rbacv1.ClusterRoleBinding, error) {
 	binding := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: GenBindingName(username, roleName, ldapGroundBindingName),
+			Name: svc.BindingName(account, roleName, ldapGroundBindingName),
 			Labels: map[string]string{
 				saManagedLabel: "true",
 			},
+			Annotations: map[string]string{
+				ldapGroupBindingAnnotation: ldapGroundBindingName,
+			},
 		},
 		Subjects: []rbacv1.Subject{
 			{
 				Kind:      "ServiceAccount",
-				Name:      username,
-				Namespace: svc.namespace,
+				Name:      account.Name,
+				Namespace: account.Namespace,
 			},
 		},
 		RoleRef: rbacv1.RoleRef{
@@ -234,11 +418,11 @@ func (svc *serviceAccountsService) CreateClusterRoleBinding(
 // UpdateClusterRoleBinding updates an existing cluster role binding for the service account.
 func (svc *serviceAccountsService) UpdateClusterRoleBinding(
 	ctx context.Context,
-	username string,
+	account types.NamespacedName,
 	clusterRoleName string,
 	ldapGroundBindingName string,
 ) (*rbacv1.ClusterRoleBinding, error) {
-	name := GenBindingName(username, clusterRoleName, ldapGroundBindingName)
+	name := svc.BindingName(account, clusterRoleName, ldapGroundBindingName)
 	binding, err := svc.clientset.RbacV1().
 		ClusterRoleBindings().
 		Get(ctx, name, metav1.GetOptions{})
@@ -248,6 +432,7 @@ func (svc *serviceAccountsService) UpdateClusterRoleBinding(
 	}
 
 	binding.RoleRef.Name = clusterRoleName
+	setAnnotation(&binding.ObjectMeta, ldapGroupBindingAnnotation, ldapGroundBindingName)
 	binding, err = svc.clientset.RbacV1().
 		ClusterRoleBindings().
 		Update(ctx, binding, metav1.UpdateOptions{})
@@ -280,7 +465,7 @@ func (svc *serviceAccountsService) DeleteClusterRoleBinding(
 // GetRoleBindings retrieves the role bindings accross all namespaces for a service account.
 func (svc *serviceAccountsService) GetRoleBindings(
 	ctx context.Context,
-	username string,
+	account types.NamespacedName,
 ) ([]rbacv1.RoleBinding, error) {
 	bindings, err := svc.clientset.RbacV1().
 		RoleBindings("").
@@ -294,19 +479,15 @@ func (svc *serviceAccountsService) GetRoleBindings(
 
 	var filteredBindings []rbacv1.RoleBinding
 	for _, binding := range bindings.Items {
-		for _, subject := range binding.Subjects {
-			if subject.Kind == "ServiceAccount" && subject.Name == username &&
-				subject.Namespace == svc.namespace {
-				filteredBindings = append(filteredBindings, binding)
-				break
-			}
+		if IsSubject(binding.Subjects, account) {
+			filteredBindings = append(filteredBindings, binding)
 		}
 	}
 
 	svc.logger.Info(
 		"Retrieved role bindings",
-		"username",
-		username,
+		"serviceAccount",
+		account,
 		"count",
 		len(filteredBindings),
 	)
@@ -316,24 +497,27 @@ func (svc *serviceAccountsService) GetRoleBindings(
 // CreateRoleBinding creates a role binding for the service account.
 func (svc *serviceAccountsService) CreateRoleBinding(
 	ctx context.Context,
-	username string,
+	account types.NamespacedName,
 	roleBindingNamespace string,
 	ldapGroundBindingName string,
 	roleRef rbacv1.RoleRef,
 ) (*rbacv1.RoleBinding, error) {
-	name := GenBindingName(username, roleRef.Name, ldapGroundBindingName)
-	saNamespace := svc.namespace
+	name := svc.BindingName(account, roleRef.Name, ldapGroundBindingName)
+	saNamespace := account.Namespace
 	binding := &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: name,
 			Labels: map[string]string{
 				saManagedLabel: "true",
 			},
+			Annotations: map[string]string{
+				ldapGroupBindingAnnotation: ldapGroundBindingName,
+			},
 		},
 		Subjects: []rbacv1.Subject{
 			{
 				Kind:      "ServiceAccount",
-				Name:      username,
+				Name:      account.Name,
 				Namespace: saNamespace,
 			},
 		},
@@ -363,12 +547,12 @@ func (svc *serviceAccountsService) CreateRoleBinding(
 // UpdateRoleBinding updates an existing role binding for the service account.
 func (svc *serviceAccountsService) UpdateRoleBinding(
 	ctx context.Context,
-	username string,
+	account types.NamespacedName,
 	roleBindingNamespace string,
 	roleRef rbacv1.RoleRef,
 	ldapGroundBindingName string,
 ) (*rbacv1.RoleBinding, error) {
-	name := GenBindingName(username, roleRef.Name, ldapGroundBindingName)
+	name := svc.BindingName(account, roleRef.Name, ldapGroundBindingName)
 	binding, err := svc.clientset.RbacV1().
 		RoleBindings(roleBindingNamespace).
 		Get(ctx, name, metav1.GetOptions{})
@@ -378,11 +562,12 @@ func (svc *serviceAccountsService) UpdateRoleBinding(
 	}
 
 	binding.RoleRef = roleRef
+	setAnnotation(&binding.ObjectMeta, ldapGroupBindingAnnotation, ldapGroundBindingName)
 	binding.Subjects = []rbacv1.Subject{
 		{
 			Kind:      "ServiceAccount",
-			Name:      username,
-			Namespace: svc.namespace,
+			Name:      account.Name,
+			Namespace: account.Namespace,
 		},
 	}
 	binding, err = svc.clientset.RbacV1().
@@ -400,7 +585,7 @@ func (svc *serviceAccountsService) UpdateRoleBinding(
 		"roleBindingNamespace",
 		roleBindingNamespace,
 		"saNamespace",
-		svc.namespace,
+		account.Namespace,
 	)
 	return binding, nil
 }
@@ -423,15 +608,71 @@ func (svc *serviceAccountsService) DeleteRoleBinding(
 	return nil
 }
 
+// GetLdapGroupBindingBindings retrieves the bindings generated from an LdapGroupBinding.
+func (svc *serviceAccountsService) GetLdapGroupBindingBindings(
+	ctx context.Context,
+	ldapGroupBindingName string,
+) ([]rbacv1.ClusterRoleBinding, []rbacv1.RoleBinding, error) {
+	listOptions := metav1.ListOptions{
+		LabelSelector: saManagedLabel + "=true",
+	}
+
+	clusterRoleBindings, err := svc.clientset.RbacV1().
+		ClusterRoleBindings().
+		List(ctx, listOptions)
+	if err != nil {
+		svc.logger.Error("Failed to get cluster role bindings", "error", err)
+		return nil, nil, err
+	}
+	var filteredClusterRoleBindings []rbacv1.ClusterRoleBinding
+	for _, binding := range clusterRoleBindings.Items {
+		if BindingSource(binding.ObjectMeta) == ldapGroupBindingName {
+			filteredClusterRoleBindings = append(filteredClusterRoleBindings, binding)
+		}
+	}
+
+	roleBindings, err := svc.clientset.RbacV1().
+		RoleBindings("").
+		List(ctx, listOptions)
+	if err != nil {
+		svc.logger.Error("Failed to get role bindings", "error", err)
+		return nil, nil, err
+	}
+	var filteredRoleBindings []rbacv1.RoleBinding
+	for _, binding := range roleBindings.Items {
+		if BindingSource(binding.ObjectMeta) == ldapGroupBindingName {
+			filteredRoleBindings = append(filteredRoleBindings, binding)
+		}
+	}
+
+	svc.logger.Info(
+		"Retrieved bindings of LdapGroupBinding",
+		"ldapGroupBinding",
+		ldapGroupBindingName,
+		"clusterRoleBindings",
+		len(filteredClusterRoleBindings),
+		"roleBindings",
+		len(filteredRoleBindings),
+	)
+	return filteredClusterRoleBindings, filteredRoleBindings, nil
+}
+
 func (svc *serviceAccountsService) createServiceAccount(
 	ctx context.Context,
-	username string,
+	account types.NamespacedName,
+	principal string,
 ) (*corev1.ServiceAccount, error) {
 	sa, err := svc.clientset.CoreV1().
-		ServiceAccounts(svc.namespace).
+		ServiceAccounts(account.Namespace).
 		Create(ctx, &corev1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: username,
+				Name: account.Name,
+				Labels: map[string]string{
+					saManagedLabel: "true",
+				},
+				Annotations: map[string]string{
+					principalAnnotation: principal,
+				},
 			},
 		}, metav1.CreateOptions{})
 	if err != nil {
@@ -443,10 +684,71 @@ func (svc *serviceAccountsService) createServiceAccount(
 	return sa, nil
 }
 
+// BindingName returns the name of the bindings generated for a service account.
+// Service accounts outside the kerbernetes namespace are qualified with their namespace.
+func (svc *serviceAccountsService) BindingName(
+	account types.NamespacedName,
+	roleName string,
+	ldapGroundBindingName string,
+) string {
+	username := account.Name
+	if account.Namespace != svc.namespace {
+		username = account.Namespace + ":" + account.Name
+	}
+	return GenBindingName(username, roleName, ldapGroundBindingName)
+}
+
+// IsSubject reports whether the service account is among subjects.
+func IsSubject(subjects []rbacv1.Subject, account types.NamespacedName) bool {
+	for _, subject := range subjects {
+		if subject.Kind == "ServiceAccount" && subject.Name == account.Name &&
+			subject.Namespace == account.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
 func GenBindingName(username string, roleName string, ldapGroundBindingName string) string {
 	return fmt.Sprintf("kerbernetes:%s:%s:%s", username, ldapGroundBindingName, roleName)
 }
 
+// RecordedGroups returns the groups a service account was last reconciled with.
+// ok is false when the groups were never recorded.
+func RecordedGroups(sa *corev1.ServiceAccount) (groups []string, ok bool) {
+	raw, exists := sa.Annotations[groupsAnnotation]
+	if !exists {
+		return nil, false
+	}
+	if err := json.Unmarshal([]byte(raw), &groups); err != nil {
+		return nil, false
+	}
+	return groups, true
+}
+
+// BindingSource returns the name of the LdapGroupBinding a binding was generated from.
+// Bindings created before the annotation existed fall back to parsing the binding name.
+func BindingSource(meta metav1.ObjectMeta) string {
+	if name, ok := meta.Annotations[ldapGroupBindingAnnotation]; ok {
+		return name
+	}
+	// kerbernetes:<username>:<ldapGroupBinding>:<role>, role names may contain colons.
+	// Bindings of service accounts outside the kerbernetes namespace always carry the
+	// annotation.
+	parts := strings.SplitN(meta.Name, ":", 4)
+	if len(parts) != 4 || parts[0] != "kerbernetes" {
+		return ""
+	}
+	return parts[2]
+}
+
+func setAnnotation(meta *metav1.ObjectMeta, key string, value string) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[key] = value
+}
+
 // int64Ptr is a helper function to create a pointer to an int64 value.
 func int64Ptr(i int64) *int64 {
 	return &i
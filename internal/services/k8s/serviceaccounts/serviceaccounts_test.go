@@ -0,0 +1,123 @@
+package serviceaccountssvc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUpsertServiceAccount(t *testing.T) {
+	const principal = "alice@EXAMPLE.COM"
+	hashed := HashedName("alice", principal)
+
+	serviceAccount := func(name string, labels, annotations map[string]string) *corev1.ServiceAccount {
+		return &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "kerbernetes",
+			Labels:      labels,
+			Annotations: annotations,
+		}}
+	}
+	managed := map[string]string{saManagedLabel: "true"}
+
+	tests := []struct {
+		name      string
+		principal string
+		existing  []runtime.Object
+		want      string
+		// untouched is a service account that must keep its labels and annotations
+		untouched string
+	}{
+		{
+			name:      "missing service account is created",
+			principal: principal,
+			want:      "alice",
+		},
+		{
+			name:      "service account of the principal is reused",
+			principal: principal,
+			existing: []runtime.Object{
+				serviceAccount("alice", managed, map[string]string{principalAnnotation: principal}),
+			},
+			want: "alice",
+		},
+		{
+			name:      "managed service account without principal is adopted",
+			principal: principal,
+			existing:  []runtime.Object{serviceAccount("alice", managed, nil)},
+			want:      "alice",
+		},
+		{
+			name:      "unmanaged service account is not taken over",
+			principal: principal,
+			existing:  []runtime.Object{serviceAccount("alice", nil, nil)},
+			want:      hashed,
+			untouched: "alice",
+		},
+		{
+			name:      "default service account is not taken over",
+			principal: "default@EXAMPLE.COM",
+			existing:  []runtime.Object{serviceAccount("default", nil, nil)},
+			want:      HashedName("default", "default@EXAMPLE.COM"),
+			untouched: "default",
+		},
+		{
+			name:      "service account of another principal is not taken over",
+			principal: principal,
+			existing: []runtime.Object{
+				serviceAccount(
+					"alice",
+					managed,
+					map[string]string{principalAnnotation: "alice@OTHER.COM"},
+				),
+			},
+			want: hashed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(tt.existing...)
+			svc := &serviceAccountsService{
+				clientset: clientset,
+				namespace: "kerbernetes",
+				logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+
+			sa, err := svc.UpsertServiceAccount(context.Background(), tt.principal, "", "")
+			if err != nil {
+				t.Fatalf("UpsertServiceAccount: %v", err)
+			}
+			if sa.Name != tt.want {
+				t.Errorf("service account = %q, want %q", sa.Name, tt.want)
+			}
+			if sa.Labels[saManagedLabel] != "true" || Principal(sa) != tt.principal {
+				t.Errorf("service account %q is not managed for %s", sa.Name, tt.principal)
+			}
+
+			if tt.untouched == "" {
+				return
+			}
+			other, err := clientset.CoreV1().
+				ServiceAccounts("kerbernetes").
+				Get(context.Background(), tt.untouched, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get %s: %v", tt.untouched, err)
+			}
+			if len(other.Labels) > 0 || len(other.Annotations) > 0 {
+				t.Errorf(
+					"service account %q was modified: %v %v",
+					tt.untouched,
+					other.Labels,
+					other.Annotations,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package signingkeyssvc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/samber/do"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// secretKey is the key of the Secret holding the signing keys
+	secretKey    = "keys.json"
+	managedLabel = "kerbernetes.io/managed"
+
+	// Algorithm is the signature algorithm of every signing key
+	Algorithm = jose.ES256
+
+	// syncInterval is the period of the rotation check, which also picks up the keys
+	// rotated by other replicas
+	syncInterval = time.Minute
+	// publishDelay is how long a new key is only published before it signs, so that every
+	// replica serves it by the time verifiers see it
+	publishDelay = 2 * syncInterval
+	// reloadBackoff limits the reloads triggered by tokens signed with an unknown key
+	reloadBackoff = 10 * time.Second
+	syncAttempts  = 3
+)
+
+var (
+	// ErrNoSigningKey is returned until the signing keys are loaded
+	ErrNoSigningKey = errors.New("no signing key is loaded")
+	// ErrUnknownKey is returned for a key ID that is not retained
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+// storedKey is a signing key as persisted in the Secret
+type storedKey struct {
+	Created time.Time       `json:"created"`
+	Key     jose.JSONWebKey `json:"key"`
+}
+
+type SigningKeysSvc interface {
+	// Start loads the signing keys, creating them when missing, and rotates them until the
+	// context is cancelled. It returns immediately when no feature signs tokens.
+	Start(ctx context.Context) error
+
+	// Enabled reports whether a feature signs tokens
+	Enabled() bool
+
+	// Signer returns a signer using the newest signing key published for long enough
+	Signer() (jose.Signer, error)
+
+	// PublicKeys returns the public part of every retained signing key
+	PublicKeys() jose.JSONWebKeySet
+
+	// PublicKey returns the public part of a signing key, reloading the keys once when it
+	// is unknown in case another replica rotated them
+	PublicKey(ctx context.Context, keyID string) (*jose.JSONWebKey, error)
+}
+
+type signingKeysSvc struct {
+	env       envsvc.Env
+	clientset *kubernetes.Clientset
+	namespace string
+	logger    *slog.Logger
+
+	// keys holds the retained keys, newest first
+	keys atomic.Pointer[[]storedKey]
+
+	// mu serializes the reads and writes of the Secret
+	mu         sync.Mutex
+	lastReload time.Time
+}
+
+func NewProvider() func(i *do.Injector) (SigningKeysSvc, error) {
+	return func(i *do.Injector) (SigningKeysSvc, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[k8ssvc.K8sService](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+func New(
+	envSvc envsvc.EnvSvc,
+	k8sSvc k8ssvc.K8sService,
+	logger *slog.Logger,
+) (SigningKeysSvc, error) {
+	return &signingKeysSvc{
+		env:       envSvc.GetEnv(),
+		clientset: k8sSvc.GetClientset(),
+		namespace: k8sSvc.GetNamespace(),
+		logger:    logger.With("service", "signingkeys"),
+	}, nil
+}
+
+func (svc *signingKeysSvc) Enabled() bool {
+	return svc.env.CredentialMode == envsvc.CredentialModeWebhook ||
+		svc.env.CredentialMode == envsvc.CredentialModeOIDC
+}
+
+func (svc *signingKeysSvc) Start(ctx context.Context) error {
+	if !svc.Enabled() {
+		return nil
+	}
+
+	svc.logger.Info(
+		"Managing signing keys",
+		"secret", svc.env.SigningKeysSecretName,
+		"rotationInterval", svc.rotationInterval(),
+	)
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		// a failed sync keeps the loaded keys, tokens are refused until a key is loaded
+		if err := svc.sync(ctx); err != nil {
+			svc.logger.Error("Failed to sync signing keys", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (svc *signingKeysSvc) Signer() (jose.Signer, error) {
+	keys := svc.keys.Load()
+	if keys == nil || len(*keys) == 0 {
+		return nil, ErrNoSigningKey
+	}
+	current := (*keys)[0].Key
+	for _, key := range *keys {
+		if time.Since(key.Created) >= publishDelay {
+			current = key.Key
+			break
+		}
+	}
+	return jose.NewSigner(
+		jose.SigningKey{Algorithm: Algorithm, Key: &current},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+}
+
+func (svc *signingKeysSvc) PublicKeys() jose.JSONWebKeySet {
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	keys := svc.keys.Load()
+	if keys == nil {
+		return set
+	}
+	for _, key := range *keys {
+		set.Keys = append(set.Keys, key.Key.Public())
+	}
+	return set
+}
+
+func (svc *signingKeysSvc) PublicKey(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
+	if key, ok := svc.lookup(keyID); ok {
+		return key, nil
+	}
+
+	svc.mu.Lock()
+	if time.Since(svc.lastReload) >= reloadBackoff {
+		keys, _, err := svc.read(ctx)
+		if err != nil {
+			svc.logger.Warn("Failed to reload signing keys", "error", err)
+		} else {
+			svc.store(keys)
+		}
+	}
+	svc.mu.Unlock()
+
+	if key, ok := svc.lookup(keyID); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (svc *signingKeysSvc) lookup(keyID string) (*jose.JSONWebKey, bool) {
+	keys := svc.keys.Load()
+	if keys == nil {
+		return nil, false
+	}
+	for _, key := range *keys {
+		if key.Key.KeyID == keyID {
+			public := key.Key.Public()
+			return &public, true
+		}
+	}
+	return nil, false
+}
+
+func (svc *signingKeysSvc) rotationInterval() time.Duration {
+	return time.Duration(svc.env.SigningKeyRotationInterval) * time.Second
+}
+
+// sync reads the keys from the Secret, rotates them when the current key is older than the
+// rotation interval and writes them back. Concurrent writes of other replicas are retried
+// from a fresh read, which sees their new key and does not rotate again.
+func (svc *signingKeysSvc) sync(ctx context.Context) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	var conflict error
+	for range syncAttempts {
+		keys, secret, err := svc.read(ctx)
+		if err != nil {
+			return err
+		}
+
+		next, changed, err := svc.rotate(keys, time.Now())
+		if err != nil {
+			return err
+		}
+		if !changed {
+			svc.store(keys)
+			return nil
+		}
+
+		err = svc.write(ctx, secret, next)
+		if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+			conflict = err
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		svc.store(next)
+		svc.logger.Info("Rotated signing keys", "keyID", next[0].Key.KeyID, "retained", len(next))
+		return nil
+	}
+	return fmt.Errorf("signing keys kept changing: %w", conflict)
+}
+
+// rotate prepends a new key when the current one is due and drops the keys replaced for
+// more than a rotation interval, which outlives every token they signed.
+func (svc *signingKeysSvc) rotate(keys []storedKey, now time.Time) ([]storedKey, bool, error) {
+	interval := svc.rotationInterval()
+
+	next := keys
+	changed := false
+	if len(keys) == 0 || now.Sub(keys[0].Created) >= interval {
+		key, err := generateKey(now)
+		if err != nil {
+			return nil, false, err
+		}
+		next = append([]storedKey{key}, keys...)
+		changed = true
+	}
+
+	retained := next[:1]
+	for _, key := range next[1:] {
+		if now.Sub(key.Created) < 2*interval {
+			retained = append(retained, key)
+		} else {
+			changed = true
+		}
+	}
+	return retained, changed, nil
+}
+
+// read returns the keys of the Secret, none when it does not exist yet
+func (svc *signingKeysSvc) read(ctx context.Context) ([]storedKey, *corev1.Secret, error) {
+	svc.lastReload = time.Now()
+	secret, err := svc.clientset.CoreV1().
+		Secrets(svc.namespace).
+		Get(ctx, svc.env.SigningKeysSecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, ok := secret.Data[secretKey]
+	if !ok {
+		return nil, secret, nil
+	}
+	var keys []storedKey
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, nil, fmt.Errorf("secret %s has invalid signing keys: %w", secret.Name, err)
+	}
+	return keys, secret, nil
+}
+
+// write creates the Secret or updates it at the read resource version
+func (svc *signingKeysSvc) write(
+	ctx context.Context,
+	secret *corev1.Secret,
+	keys []storedKey,
+) error {
+	raw, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	secrets := svc.clientset.CoreV1().Secrets(svc.namespace)
+	if secret == nil {
+		_, err = secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svc.env.SigningKeysSecretName,
+				Namespace: svc.namespace,
+				Labels: map[string]string{
+					managedLabel: "true",
+				},
+			},
+			Data: map[string][]byte{secretKey: raw},
+		}, metav1.CreateOptions{})
+		return err
+	}
+
+	secret = secret.DeepCopy()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[secretKey] = raw
+	_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+func (svc *signingKeysSvc) store(keys []storedKey) {
+	svc.keys.Store(&keys)
+}
+
+func generateKey(now time.Time) (storedKey, error) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return storedKey{}, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	key := jose.JSONWebKey{
+		Key:       private,
+		Algorithm: string(Algorithm),
+		Use:       "sig",
+	}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return storedKey{}, fmt.Errorf("failed to compute signing key ID: %w", err)
+	}
+	key.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	return storedKey{Created: now.UTC(), Key: key}, nil
+}
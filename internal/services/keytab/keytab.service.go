@@ -0,0 +1,252 @@
+package keytabsvc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/samber/do"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+type KeytabSvc interface {
+	// Start watches the keytab source and reloads it when it changes
+	Start(ctx context.Context) error
+
+	// Keytab returns the keytab used to accept tickets, nil when none could be loaded.
+	// During a rotation window it holds the keys of both the new and the old keytab.
+	Keytab() *keytab.Keytab
+}
+
+// snapshot is swapped atomically on every reload
+type snapshot struct {
+	// active is the last loaded keytab
+	active *keytab.Keytab
+	// merged holds the keys of active and of the previous keytab until mergedUntil
+	merged      *keytab.Keytab
+	mergedUntil time.Time
+}
+
+type keytabSvc struct {
+	env    envsvc.Env
+	k8sSvc k8ssvc.K8sService
+	logger *slog.Logger
+
+	current atomic.Pointer[snapshot]
+
+	// mu serializes reloads, raw is the content of the active keytab and invalid the
+	// content that last failed to load
+	mu      sync.Mutex
+	raw     []byte
+	invalid []byte
+}
+
+func NewProvider() func(i *do.Injector) (KeytabSvc, error) {
+	return func(i *do.Injector) (KeytabSvc, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[k8ssvc.K8sService](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+// New loads the keytab from its source. A keytab that fails to load is logged rather than
+// fatal, authentication is refused until a valid keytab is loaded.
+func New(
+	configService envsvc.EnvSvc,
+	k8sSvc k8ssvc.K8sService,
+	logger *slog.Logger,
+) (KeytabSvc, error) {
+	svc := &keytabSvc{
+		env:    configService.GetEnv(),
+		k8sSvc: k8sSvc,
+		logger: logger.With("service", "keytab"),
+	}
+
+	raw, err := svc.read(context.Background())
+	if err != nil {
+		svc.logger.Error("Failed to read keytab", "error", err)
+		return svc, nil
+	}
+	if err := svc.load(raw); err != nil {
+		svc.logger.Error("Failed to load keytab", "error", err)
+	}
+	return svc, nil
+}
+
+// Keytab returns the keytab used to accept tickets, nil when none could be loaded.
+func (s *keytabSvc) Keytab() *keytab.Keytab {
+	snap := s.current.Load()
+	if snap == nil {
+		return nil
+	}
+	if snap.merged != nil && time.Now().Before(snap.mergedUntil) {
+		return snap.merged
+	}
+	return snap.active
+}
+
+// Start watches the keytab source and reloads it when it changes.
+func (s *keytabSvc) Start(ctx context.Context) error {
+	if s.env.KeytabSecretName != "" {
+		return s.watchSecret(ctx)
+	}
+	return s.watchFile(ctx)
+}
+
+// read returns the raw keytab from the Secret when configured, from the file otherwise
+func (s *keytabSvc) read(ctx context.Context) ([]byte, error) {
+	if s.env.KeytabSecretName == "" {
+		return os.ReadFile(s.env.KeytabPath)
+	}
+	secret, err := s.k8sSvc.GetClientset().CoreV1().
+		Secrets(s.k8sSvc.GetNamespace()).
+		Get(ctx, s.env.KeytabSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return secretKeytab(secret, s.env.KeytabSecretKey)
+}
+
+func secretKeytab(secret *corev1.Secret, key string) ([]byte, error) {
+	raw, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no key %s", secret.Name, key)
+	}
+	return raw, nil
+}
+
+// load parses a keytab and swaps it in if its content changed. The previous keytab stays
+// accepted for the rotation window so that tickets issued before the rotation still work.
+func (s *keytabSvc) load(raw []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if bytes.Equal(raw, s.raw) || bytes.Equal(raw, s.invalid) {
+		return nil
+	}
+
+	kt := keytab.New()
+	if err := kt.Unmarshal(raw); err != nil {
+		s.invalid = raw
+		return err
+	}
+
+	next := &snapshot{active: kt}
+	previous := s.current.Load()
+	if previous != nil && s.env.KeytabRotationWindow > 0 {
+		// the newest entry wins for a given kvno, old kvnos keep resolving to old keys
+		merged := keytab.New()
+		merged.Entries = append(merged.Entries, kt.Entries...)
+		merged.Entries = append(merged.Entries, previous.active.Entries...)
+		next.merged = merged
+		next.mergedUntil = time.Now().Add(
+			time.Duration(s.env.KeytabRotationWindow) * time.Second,
+		)
+	}
+
+	s.current.Store(next)
+	s.raw = raw
+	s.logger.Info(
+		"Loaded keytab",
+		"entries", len(kt.Entries),
+		"rotation", previous != nil,
+		"acceptPreviousUntil", next.mergedUntil,
+	)
+	return nil
+}
+
+// watchFile polls the keytab file, a mounted Secret is updated through a symlink swap
+// that file system events do not reliably report
+func (s *keytabSvc) watchFile(ctx context.Context) error {
+	if s.env.KeytabReloadInterval <= 0 {
+		s.logger.Info("Keytab reload disabled", "path", s.env.KeytabPath)
+		<-ctx.Done()
+		return nil
+	}
+
+	interval := time.Duration(s.env.KeytabReloadInterval) * time.Second
+	s.logger.Info("Watching keytab file", "path", s.env.KeytabPath, "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		raw, err := os.ReadFile(s.env.KeytabPath)
+		if err != nil {
+			s.logger.Error("Failed to read keytab", "path", s.env.KeytabPath, "error", err)
+			continue
+		}
+		if err := s.load(raw); err != nil {
+			s.logger.Error("Failed to load keytab, keeping the current one", "error", err)
+		}
+	}
+}
+
+// watchSecret reloads the keytab on every change of the Secret
+func (s *keytabSvc) watchSecret(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		s.k8sSvc.GetClientset(),
+		0,
+		informers.WithNamespace(s.k8sSvc.GetNamespace()),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = "metadata.name=" + s.env.KeytabSecretName
+		}),
+	)
+	informer := factory.Core().V1().Secrets().Informer()
+
+	onChange := func(obj interface{}) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			return
+		}
+		raw, err := secretKeytab(secret, s.env.KeytabSecretKey)
+		if err != nil {
+			s.logger.Error("Failed to read keytab from secret", "error", err)
+			return
+		}
+		if err := s.load(raw); err != nil {
+			s.logger.Error("Failed to load keytab, keeping the current one", "error", err)
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: onChange,
+		UpdateFunc: func(_, newObj interface{}) {
+			onChange(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			s.logger.Warn("Keytab secret deleted, keeping the current keytab")
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch keytab secret: %w", err)
+	}
+
+	s.logger.Info(
+		"Watching keytab secret",
+		"secret", s.env.KeytabSecretName,
+		"key", s.env.KeytabSecretKey,
+	)
+	stopCh := make(chan struct{})
+	factory.Start(stopCh)
+	<-ctx.Done()
+	close(stopCh)
+	return nil
+}
@@ -0,0 +1,213 @@
+package kubeconfigsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/froz42/kerbernetes/internal/security"
+	authsvc "github.com/froz42/kerbernetes/internal/services/auth"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	k8smodels "github.com/froz42/kerbernetes/internal/services/k8s/models"
+	serviceaccountssvc "github.com/froz42/kerbernetes/internal/services/k8s/serviceaccounts"
+	"github.com/samber/do"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientcmdv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// fallbackNamespace is the context namespace of users without any RoleBinding
+const fallbackNamespace = "default"
+
+type KubeconfigSvc interface {
+	// Generate returns a kubeconfig for a principal whose credentials are fetched by the
+	// exec credential plugin from authURL
+	Generate(ctx context.Context, principal string, authURL string) (*clientcmdv1.Config, error)
+}
+
+type kubeconfigSvc struct {
+	env                envsvc.Env
+	authSvc            authsvc.AuthService
+	serviceAccountsSvc serviceaccountssvc.ServiceAccountsService
+	logger             *slog.Logger
+
+	server   string
+	caData   []byte
+	insecure bool
+}
+
+func NewProvider() func(i *do.Injector) (KubeconfigSvc, error) {
+	return func(i *do.Injector) (KubeconfigSvc, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[k8ssvc.K8sService](i),
+			do.MustInvoke[authsvc.AuthService](i),
+			do.MustInvoke[serviceaccountssvc.ServiceAccountsService](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+func New(
+	envSvc envsvc.EnvSvc,
+	k8sSvc k8ssvc.K8sService,
+	authSvc authsvc.AuthService,
+	serviceAccountsSvc serviceaccountssvc.ServiceAccountsService,
+	logger *slog.Logger,
+) (KubeconfigSvc, error) {
+	env := envSvc.GetEnv()
+	restConfig := k8sSvc.GetRestConfig()
+
+	svc := &kubeconfigSvc{
+		env:                env,
+		authSvc:            authSvc,
+		serviceAccountsSvc: serviceAccountsSvc,
+		logger:             logger.With("service", "kubeconfig"),
+		server:             env.KubeconfigServer,
+		caData:             restConfig.CAData,
+		insecure:           restConfig.Insecure,
+	}
+
+	// the in-cluster address is only a fallback, it is rarely reachable by users
+	if svc.server == "" {
+		svc.server = restConfig.Host
+	}
+
+	caPath := env.KubeconfigCAPath
+	if caPath == "" && len(svc.caData) == 0 {
+		caPath = restConfig.CAFile
+	}
+	if caPath != "" {
+		caData, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubeconfig CA bundle: %w", err)
+		}
+		svc.caData = caData
+		svc.insecure = false
+	}
+
+	return svc, nil
+}
+
+func (svc *kubeconfigSvc) Generate(
+	ctx context.Context,
+	principal string,
+	authURL string,
+) (*clientcmdv1.Config, error) {
+	// the service account and its bindings are reconciled below, as when issuing credentials
+	if err := svc.authSvc.CheckAccount(principal); err != nil {
+		return nil, err
+	}
+
+	var user string
+	var roleBindings []rbacv1.RoleBinding
+	var err error
+	if svc.env.UsesGroupSubjects() {
+		user, _ = security.SplitPrincipal(principal)
+		roleBindings, err = svc.groupRoleBindings(ctx, principal)
+	} else {
+		var account types.NamespacedName
+		account, err = svc.authSvc.ReconcileAccount(ctx, principal)
+		if err != nil {
+			return nil, err
+		}
+		user = account.Name
+		roleBindings, err = svc.serviceAccountsSvc.GetRoleBindings(ctx, account)
+	}
+	if err != nil {
+		var statusErr huma.StatusError
+		if errors.As(err, &statusErr) {
+			return nil, err
+		}
+		svc.logger.Error("Failed to get role bindings", "principal", principal, "error", err)
+		return nil, huma.Error500InternalServerError("Failed to get role bindings")
+	}
+	namespace := defaultNamespace(roleBindings)
+
+	clusterName := svc.env.KubeconfigClusterName
+	userName := clusterName + "-" + user
+	svc.logger.Info(
+		"Generated kubeconfig",
+		"principal", principal,
+		"namespace", namespace,
+	)
+
+	return &clientcmdv1.Config{
+		Kind:           "Config",
+		APIVersion:     "v1",
+		CurrentContext: clusterName,
+		Clusters: []clientcmdv1.NamedCluster{{
+			Name: clusterName,
+			Cluster: clientcmdv1.Cluster{
+				Server:                   svc.server,
+				CertificateAuthorityData: svc.caData,
+				InsecureSkipTLSVerify:    svc.insecure,
+			},
+		}},
+		AuthInfos: []clientcmdv1.NamedAuthInfo{{
+			Name: userName,
+			AuthInfo: clientcmdv1.AuthInfo{
+				Exec: &clientcmdv1.ExecConfig{
+					APIVersion:      k8smodels.ExecCredentialV1,
+					Command:         svc.env.KubeconfigExecCommand,
+					Args:            []string{authURL, clusterName},
+					InstallHint:     "Install the kerbernetes credential plugin and run kinit",
+					InteractiveMode: clientcmdv1.NeverExecInteractiveMode,
+				},
+			},
+		}},
+		Contexts: []clientcmdv1.NamedContext{{
+			Name: clusterName,
+			Context: clientcmdv1.Context{
+				Cluster:   clusterName,
+				AuthInfo:  userName,
+				Namespace: namespace,
+			},
+		}},
+	}, nil
+}
+
+// groupRoleBindings retrieves the role bindings granted to the groups of a principal.
+func (svc *kubeconfigSvc) groupRoleBindings(
+	ctx context.Context,
+	principal string,
+) ([]rbacv1.RoleBinding, error) {
+	groups, _, err := svc.authSvc.ResolveGroups(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	return svc.serviceAccountsSvc.GetGroupRoleBindings(ctx, groups)
+}
+
+// defaultNamespace returns the namespace holding the most role bindings, ties are broken
+// alphabetically.
+func defaultNamespace(roleBindings []rbacv1.RoleBinding) string {
+	counts := make(map[string]int)
+	for _, binding := range roleBindings {
+		counts[binding.Namespace]++
+	}
+	if len(counts) == 0 {
+		return fallbackNamespace
+	}
+
+	namespaces := make([]string, 0, len(counts))
+	for namespace := range counts {
+		namespaces = append(namespaces, namespace)
+	}
+	slices.Sort(namespaces)
+	best := namespaces[0]
+	for _, namespace := range namespaces[1:] {
+		if counts[namespace] > counts[best] {
+			best = namespace
+		}
+	}
+	return best
+}
@@ -0,0 +1,105 @@
+package kubeconfigsvc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	authsvc "github.com/froz42/kerbernetes/internal/services/auth"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDefaultNamespace(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []string
+		want       string
+	}{
+		{name: "no role binding", namespaces: nil, want: fallbackNamespace},
+		{name: "single namespace", namespaces: []string{"dev"}, want: "dev"},
+		{
+			name:       "namespace with the most role bindings",
+			namespaces: []string{"dev", "prod", "prod", "staging"},
+			want:       "prod",
+		},
+		{
+			name:       "tie is broken alphabetically",
+			namespaces: []string{"staging", "dev", "staging", "dev"},
+			want:       "dev",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roleBindings := make([]rbacv1.RoleBinding, 0, len(tt.namespaces))
+			for _, namespace := range tt.namespaces {
+				roleBindings = append(roleBindings, rbacv1.RoleBinding{
+					ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+				})
+			}
+
+			if got := defaultNamespace(roleBindings); got != tt.want {
+				t.Errorf("defaultNamespace(%v) = %q, want %q", tt.namespaces, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeAuth refuses disabled principals and records the reconciled ones
+type fakeAuth struct {
+	authsvc.AuthService
+	disabled   string
+	reconciled []string
+}
+
+func (f *fakeAuth) CheckAccount(principal string) error {
+	if principal == f.disabled {
+		return huma.Error403Forbidden("directory account is disabled or locked")
+	}
+	return nil
+}
+
+func (f *fakeAuth) ReconcileAccount(
+	ctx context.Context,
+	principal string,
+) (types.NamespacedName, error) {
+	f.reconciled = append(f.reconciled, principal)
+	return types.NamespacedName{}, huma.Error500InternalServerError("not reconciled in tests")
+}
+
+func TestGenerateChecksAccount(t *testing.T) {
+	tests := []struct {
+		name           string
+		credentialMode string
+	}{
+		{name: "token mode", credentialMode: envsvc.CredentialModeToken},
+		{name: "certificate mode", credentialMode: envsvc.CredentialModeCertificate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &fakeAuth{disabled: "alice@EXAMPLE.COM"}
+			svc := &kubeconfigSvc{
+				env:     envsvc.Env{CredentialMode: tt.credentialMode},
+				authSvc: auth,
+				logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+
+			_, err := svc.Generate(context.Background(), "alice@EXAMPLE.COM", "https://auth")
+			var statusErr huma.StatusError
+			if !errors.As(err, &statusErr) || statusErr.GetStatus() != http.StatusForbidden {
+				t.Fatalf("Generate error = %v, want a 403", err)
+			}
+			if len(auth.reconciled) > 0 {
+				t.Errorf("the disabled account was reconciled")
+			}
+		})
+	}
+}
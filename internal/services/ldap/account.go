@@ -0,0 +1,88 @@
+package ldapsvc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// userAccountControlDisabled is the ACCOUNTDISABLE flag of the Active Directory
+// userAccountControl attribute
+const userAccountControlDisabled = 0x2
+
+// accountCheck reads an account state attribute of a user
+type accountCheck struct {
+	attribute string
+	// disabled returns why a value of the attribute disables the account, or an empty
+	// string
+	disabled func(value string) string
+}
+
+var accountChecks = map[string]accountCheck{
+	envsvc.AccountCheckActiveDirectory: {
+		attribute: "userAccountControl",
+		disabled: func(value string) string {
+			flags, err := strconv.ParseInt(value, 10, 64)
+			if err == nil && flags&userAccountControlDisabled != 0 {
+				return "account is disabled"
+			}
+			return ""
+		},
+	},
+	envsvc.AccountCheckNSAccountLock: {
+		attribute: "nsAccountLock",
+		disabled: func(value string) string {
+			if strings.EqualFold(value, "true") {
+				return "account is locked"
+			}
+			return ""
+		},
+	},
+	envsvc.AccountCheckPPolicy: {
+		// the password policy overlay sets it on lockout, until an administrator or the
+		// lockout duration unlocks the account on its next bind
+		attribute: "pwdAccountLockedTime",
+		disabled: func(value string) string {
+			if value != "" {
+				return "account is locked"
+			}
+			return ""
+		},
+	},
+	envsvc.AccountCheckShadow: {
+		// days since the epoch from which the account is expired
+		attribute: "shadowExpire",
+		disabled: func(value string) string {
+			days, err := strconv.ParseInt(value, 10, 64)
+			if err == nil && days > 0 && time.Now().Unix()/86400 >= days {
+				return "account is expired"
+			}
+			return ""
+		},
+	},
+}
+
+// userAttributes returns the attributes fetched with users
+func (s *ldapSvc) userAttributes() []string {
+	attributes := []string{"dn"}
+	for _, check := range s.env.LDAPAccountCheckList() {
+		attributes = append(attributes, accountChecks[check].attribute)
+	}
+	return attributes
+}
+
+// DisabledReason returns why the account of a user is disabled, locked or expired
+func (s *ldapSvc) DisabledReason(user *ldap.Entry) string {
+	for _, name := range s.env.LDAPAccountCheckList() {
+		check := accountChecks[name]
+		for _, value := range user.GetEqualFoldAttributeValues(check.attribute) {
+			if reason := check.disabled(value); reason != "" {
+				return check.attribute + ": " + reason
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package ldapsvc
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cache keeps lookup results for a TTL, results such as an unknown user being kept for the
+// negative TTL instead. Concurrent lookups of a key share a single load, and the least
+// recently used entries are evicted beyond maxEntries.
+type cache[V any] struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+	// negative reports whether a result is negative, other errors are not cached
+	negative func(value V, err error) bool
+
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// generation changes on purges so that loads started before are not cached
+	generation uint64
+}
+
+type cacheEntry[V any] struct {
+	key     string
+	value   V
+	err     error
+	expires time.Time
+}
+
+// newCache returns a cache, a negative ttl disables it and a negative negativeTTL disables
+// the caching of negative results
+func newCache[V any](
+	ttl time.Duration,
+	negativeTTL time.Duration,
+	maxEntries int,
+	negative func(value V, err error) bool,
+) *cache[V] {
+	return &cache[V]{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		negative:    negative,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// get returns the cached result of key, or the one of load
+func (c *cache[V]) get(key string, load func() (V, error)) (V, error) {
+	if c.ttl < 0 {
+		return load()
+	}
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry[V])
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(element)
+			c.mu.Unlock()
+			return entry.value, entry.err
+		}
+		c.remove(element)
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	result, err, _ := c.group.Do(key, func() (any, error) {
+		value, err := load()
+		c.store(key, value, err, generation)
+		return value, err
+	})
+	return result.(V), err
+}
+
+// refresh returns the result of load whether key is cached or not, and caches it
+func (c *cache[V]) refresh(key string, load func() (V, error)) (V, error) {
+	if c.ttl < 0 {
+		return load()
+	}
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	value, err := load()
+	c.store(key, value, err, generation)
+	return value, err
+}
+
+// store caches the result of a load, other errors than negative results are not cached
+func (c *cache[V]) store(key string, value V, err error, generation uint64) {
+	switch {
+	case c.negative(value, err):
+		if c.negativeTTL >= 0 {
+			c.set(key, value, err, c.negativeTTL, generation)
+		}
+	case err == nil:
+		c.set(key, value, nil, c.ttl, generation)
+	}
+}
+
+func (c *cache[V]) set(key string, value V, err error, ttl time.Duration, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	entry := &cacheEntry[V]{key: key, value: value, err: err, expires: time.Now().Add(ttl)}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// peek returns the unexpired cached value of key
+func (c *cache[V]) peek(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry[V])
+		if entry.err == nil && time.Now().Before(entry.expires) {
+			return entry.value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// purge removes key, loads in progress are not cached
+func (c *cache[V]) purge(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.group.Forget(key)
+	element, ok := c.entries[key]
+	if ok {
+		c.remove(element)
+	}
+	return ok
+}
+
+func (c *cache[V]) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry[V]).key)
+}
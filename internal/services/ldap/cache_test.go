@@ -0,0 +1,243 @@
+package ldapsvc
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("not found")
+
+// isNotFound reports lookups of unknown keys as negative results
+func isNotFound(_ string, err error) bool {
+	return errors.Is(err, errNotFound)
+}
+
+func TestCacheGet(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		negativeTTL time.Duration
+		value       string
+		err         error
+		// wantLoads is the number of loads of two consecutive gets
+		wantLoads int
+	}{
+		{name: "value is cached", ttl: time.Minute, value: "alice", wantLoads: 1},
+		{name: "negative ttl disables the cache", ttl: -1, value: "alice", wantLoads: 2},
+		{
+			name:        "negative result is cached",
+			ttl:         time.Minute,
+			negativeTTL: time.Minute,
+			err:         errNotFound,
+			wantLoads:   1,
+		},
+		{
+			name:        "negative result caching can be disabled",
+			ttl:         time.Minute,
+			negativeTTL: -1,
+			err:         errNotFound,
+			wantLoads:   2,
+		},
+		{
+			name:        "other error is not cached",
+			ttl:         time.Minute,
+			negativeTTL: time.Minute,
+			err:         errUnavailable,
+			wantLoads:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCache(tt.ttl, tt.negativeTTL, 10, isNotFound)
+			loads := 0
+			load := func() (string, error) {
+				loads++
+				return tt.value, tt.err
+			}
+
+			for range 2 {
+				value, err := c.get("alice", load)
+				if value != tt.value || !errors.Is(err, tt.err) {
+					t.Fatalf("get = %q, %v, want %q, %v", value, err, tt.value, tt.err)
+				}
+			}
+			if loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", loads, tt.wantLoads)
+			}
+		})
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "value"},
+		{name: "negative result", err: errNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCache(10*time.Millisecond, 10*time.Millisecond, 10, isNotFound)
+			loads := 0
+			load := func() (string, error) {
+				loads++
+				return "", tt.err
+			}
+
+			_, _ = c.get("alice", load)
+			time.Sleep(20 * time.Millisecond)
+			_, _ = c.get("alice", load)
+			if loads != 2 {
+				t.Errorf("loads = %d, want the expired entry to be loaded again", loads)
+			}
+		})
+	}
+}
+
+func TestCacheSharesConcurrentLoads(t *testing.T) {
+	c := newCache(time.Minute, time.Minute, 10, isNotFound)
+	release := make(chan struct{})
+	var loads atomic.Int32
+	load := func() (string, error) {
+		loads.Add(1)
+		<-release
+		return "alice", nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if value, err := c.get("alice", load); value != "alice" || err != nil {
+				t.Errorf("get = %q, %v", value, err)
+			}
+		})
+	}
+	// let the gets join the first load before it returns
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := loads.Load(); got != 1 {
+		t.Errorf("loads = %d, want a single shared load", got)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newCache(time.Minute, time.Minute, 2, isNotFound)
+	load := func(value string) func() (string, error) {
+		return func() (string, error) { return value, nil }
+	}
+
+	_, _ = c.get("alice", load("alice"))
+	_, _ = c.get("bob", load("bob"))
+	// alice becomes the most recently used entry
+	_, _ = c.get("alice", load("alice"))
+	_, _ = c.get("carol", load("carol"))
+
+	tests := []struct {
+		key    string
+		cached bool
+	}{
+		{key: "alice", cached: true},
+		{key: "bob", cached: false},
+		{key: "carol", cached: true},
+	}
+	for _, tt := range tests {
+		if _, ok := c.peek(tt.key); ok != tt.cached {
+			t.Errorf("peek(%q) cached = %v, want %v", tt.key, ok, tt.cached)
+		}
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	tests := []struct {
+		name   string
+		cached bool
+		want   bool
+	}{
+		{name: "cached key", cached: true, want: true},
+		{name: "unknown key", cached: false, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCache(time.Minute, time.Minute, 10, isNotFound)
+			if tt.cached {
+				_, _ = c.get("alice", func() (string, error) { return "alice", nil })
+			}
+
+			if got := c.purge("alice"); got != tt.want {
+				t.Errorf("purge = %v, want %v", got, tt.want)
+			}
+			if _, ok := c.peek("alice"); ok {
+				t.Error("the purged key is still cached")
+			}
+		})
+	}
+}
+
+func TestCachePurgeDuringLoad(t *testing.T) {
+	c := newCache(time.Minute, time.Minute, 10, isNotFound)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.get("alice", func() (string, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+	}()
+
+	<-started
+	c.purge("alice")
+	close(release)
+	<-done
+
+	// the load started before the purge may have read the old state of the directory
+	if value, ok := c.peek("alice"); ok {
+		t.Errorf("load started before the purge cached %q", value)
+	}
+	value, err := c.get("alice", func() (string, error) { return "fresh", nil })
+	if value != "fresh" || err != nil {
+		t.Errorf("get = %q, %v, want a new load after the purge", value, err)
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		// want is the value of a get following the refresh
+		want string
+	}{
+		{name: "refreshed value replaces the cached one", ttl: time.Minute, want: "disabled"},
+		{name: "disabled cache", ttl: -1, want: "reloaded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCache(tt.ttl, time.Minute, 10, isNotFound)
+			_, _ = c.get("alice", func() (string, error) { return "active", nil })
+
+			value, err := c.refresh("alice", func() (string, error) { return "disabled", nil })
+			if value != "disabled" || err != nil {
+				t.Fatalf("refresh = %q, %v, want the value loaded", value, err)
+			}
+			value, _ = c.get("alice", func() (string, error) { return "reloaded", nil })
+			if value != tt.want {
+				t.Errorf("get after refresh = %q, want %q", value, tt.want)
+			}
+		})
+	}
+}
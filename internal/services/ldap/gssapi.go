@@ -0,0 +1,53 @@
+package ldapsvc
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/froz42/kerbernetes/internal/security"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldap/v3/gssapi"
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+)
+
+// gssapiBind binds a connection to server with SASL GSSAPI, authenticated as the service
+// principal of the keytab
+func (s *ldapSvc) gssapiBind(conn *ldap.Conn, server string) error {
+	kt := s.keytab()
+	if kt == nil {
+		return errors.New("no keytab loaded for the LDAP GSSAPI bind")
+	}
+	username, realm, err := s.gssapiPrincipal(kt)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return err
+	}
+
+	// a client per connection picks up keytab rotations, connections are pooled
+	cl := client.NewWithKeytab(username, realm, kt, s.krb5Conf, client.DisablePAFXFAST(true))
+	defer cl.Destroy()
+	if err := cl.Login(); err != nil {
+		return fmt.Errorf("failed to log in as %s@%s: %w", username, realm, err)
+	}
+	return conn.GSSAPIBind(&gssapi.Client{Client: cl}, "ldap/"+u.Hostname(), "")
+}
+
+// gssapiPrincipal returns the principal to bind as, the configured one or the first one of
+// the keytab
+func (s *ldapSvc) gssapiPrincipal(kt *keytab.Keytab) (username string, realm string, err error) {
+	if s.env.LDAPGSSAPIPrincipal != "" {
+		username, realm = security.SplitPrincipal(s.env.LDAPGSSAPIPrincipal)
+		return username, realm, nil
+	}
+	if len(kt.Entries) == 0 {
+		return "", "", errors.New("the keytab has no principal for the LDAP GSSAPI bind")
+	}
+	principal := kt.Entries[0].Principal
+	return strings.Join(principal.Components, "/"), principal.Realm, nil
+}
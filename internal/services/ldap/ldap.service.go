@@ -1,51 +1,150 @@
 package ldapsvc
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"slices"
+	"time"
 
 	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	keytabsvc "github.com/froz42/kerbernetes/internal/services/keytab"
 	"github.com/go-ldap/ldap/v3"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/samber/do"
 )
 
 type LDAPSvc interface {
-	// GetUser retrieves a user from LDAP by username
+	// GetUser retrieves a user from LDAP by username, with the attributes of the account
+	// state checks
 	GetUser(username string) (*ldap.Entry, error)
 
+	// RefreshUser retrieves a user from LDAP like GetUser, bypassing the cached entry which
+	// is replaced with the one read
+	RefreshUser(username string) (*ldap.Entry, error)
+
+	// DisabledReason returns why the account of a user is disabled, locked or expired
+	// according to the account state checks, or an empty string for an active account
+	DisabledReason(user *ldap.Entry) string
+
 	// GetUserGroups retrieves groups for a user from LDAP
 	GetUserGroups(dn string) ([]string, error)
+
+	// GroupExists checks whether a group DN exists in LDAP
+	GroupExists(dn string) (bool, error)
+
+	// GetGroupBySID retrieves the DN of the group with the given objectSid, or an empty
+	// string if there is none
+	GetGroupBySID(sid string) (string, error)
+
+	// PoolStats returns the statistics of the connection pool
+	PoolStats() PoolStats
+
+	// Servers returns the health of the LDAP servers, in configured or discovered order
+	Servers() []ServerStatus
+
+	// PurgeUser removes a user and its groups from the cache, it reports whether they were
+	// cached
+	PurgeUser(username string) bool
 }
 
 type ldapSvc struct {
-	env    envsvc.Env
-	logger *slog.Logger
+	env       envsvc.Env
+	keytab    func() *keytab.Keytab
+	krb5Conf  *config.Config
+	tlsConfig *tls.Config
+	servers   *servers
+	pool      *pool
+	users     *cache[*ldap.Entry]
+	groups    *cache[[]string]
+	logger    *slog.Logger
 }
 
 func NewProvider() func(i *do.Injector) (LDAPSvc, error) {
 	return func(i *do.Injector) (LDAPSvc, error) {
 		config := do.MustInvoke[envsvc.EnvSvc](i).GetEnv()
+		keytabSvc := do.MustInvoke[keytabsvc.KeytabSvc](i)
 		logger := do.MustInvoke[*slog.Logger](i)
-		return New(config, logger)
+		return New(config, keytabSvc.Keytab, logger)
 	}
 }
 
-func New(env envsvc.Env, logger *slog.Logger) (LDAPSvc, error) {
-	return &ldapSvc{
-		env:    env,
-		logger: logger.With("service", "ldap"),
-	}, nil
+// New returns an LDAP client, getKeytab returns the keytab of the GSSAPI bind
+func New(
+	env envsvc.Env,
+	getKeytab func() *keytab.Keytab,
+	logger *slog.Logger,
+) (LDAPSvc, error) {
+	tlsConfig, err := env.LDAPTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	s := &ldapSvc{
+		env:       env,
+		keytab:    getKeytab,
+		tlsConfig: tlsConfig,
+		logger:    logger.With("service", "ldap"),
+	}
+	if env.LDAPEnabled && env.LDAPBindMode == envsvc.LDAPBindModeGSSAPI {
+		s.krb5Conf, err = config.Load(env.KRB5ConfPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the Kerberos configuration: %w", err)
+		}
+	}
+	s.servers = newServers(
+		env.LDAPURLs(),
+		env.LDAPSRVDomain,
+		env.LDAPSRVService,
+		time.Duration(env.LDAPQuarantineDuration)*time.Second,
+		s.logger,
+	)
+	s.pool = newPool(
+		s.dial,
+		env.LDAPPoolSize,
+		time.Duration(env.LDAPPoolIdleTimeout)*time.Second,
+		time.Duration(env.LDAPPoolCheckInterval)*time.Second,
+	)
+
+	ttl := time.Duration(env.LDAPCacheTTL) * time.Second
+	negativeTTL := time.Duration(env.LDAPCacheNegativeTTL) * time.Second
+	s.users = newCache(ttl, negativeTTL, env.LDAPCacheMaxEntries,
+		func(_ *ldap.Entry, err error) bool {
+			return ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject)
+		},
+	)
+	s.groups = newCache(ttl, negativeTTL, env.LDAPCacheMaxEntries,
+		func(groups []string, err error) bool {
+			return err == nil && len(groups) == 0
+		},
+	)
+	return s, nil
 }
 
 // GetUser retrieves a user from LDAP by username
 func (s *ldapSvc) GetUser(username string) (*ldap.Entry, error) {
+	return s.users.get(username, func() (*ldap.Entry, error) {
+		return s.getUser(username)
+	})
+}
+
+// RefreshUser retrieves a user from LDAP bypassing the cache
+func (s *ldapSvc) RefreshUser(username string) (*ldap.Entry, error) {
+	return s.users.refresh(username, func() (*ldap.Entry, error) {
+		return s.getUser(username)
+	})
+}
+
+func (s *ldapSvc) getUser(username string) (*ldap.Entry, error) {
 	var user *ldap.Entry
 	err := s.withConnection(func(conn *ldap.Conn) error {
 		searchRequest := ldap.NewSearchRequest(
 			s.env.LDAPUserBaseDN,
 			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 			fmt.Sprintf(s.env.LDAPUserFilter, username),
-			[]string{"dn"},
+			s.userAttributes(),
 			nil,
 		)
 
@@ -71,47 +170,218 @@ func (s *ldapSvc) GetUser(username string) (*ldap.Entry, error) {
 
 // GetUserGroups retrieves groups for a user from LDAP
 func (s *ldapSvc) GetUserGroups(dn string) ([]string, error) {
+	groups, err := s.groups.get(dnKey(dn), func() ([]string, error) {
+		return s.getUserGroups(dn)
+	})
+	// callers may modify the groups
+	return slices.Clone(groups), err
+}
+
+func (s *ldapSvc) getUserGroups(dn string) ([]string, error) {
 	var groups []string
+	err := s.withConnection(func(conn *ldap.Conn) error {
+		var err error
+		switch s.env.LDAPNestedGroups {
+		case envsvc.NestedGroupsInChain:
+			groups, err = s.searchGroups(conn, fmt.Sprintf(
+				"(%s:%s:=%s)",
+				s.env.LDAPMemberAttribute,
+				matchingRuleInChain,
+				ldap.EscapeFilter(dn),
+			))
+		case envsvc.NestedGroupsRecursive:
+			groups, err = s.resolveNested(dn, func(member string) ([]string, error) {
+				return s.searchGroups(conn, fmt.Sprintf(s.env.LDAPGroupFilter, member))
+			})
+		case envsvc.NestedGroupsMemberOf:
+			groups, err = s.resolveNested(dn, func(member string) ([]string, error) {
+				return s.memberOf(conn, member)
+			})
+		default:
+			groups, err = s.searchGroups(conn, fmt.Sprintf(s.env.LDAPGroupFilter, dn))
+		}
+		return err
+	})
+
+	return groups, err
+}
+
+// GroupExists checks whether a group DN exists in LDAP
+func (s *ldapSvc) GroupExists(dn string) (bool, error) {
+	exists := false
 	err := s.withConnection(func(conn *ldap.Conn) error {
+		searchRequest := ldap.NewSearchRequest(
+			dn,
+			ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+			"(objectClass=*)",
+			[]string{"dn"},
+			nil,
+		)
+
+		result, err := conn.Search(searchRequest)
+		if err != nil {
+			if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+				return nil
+			}
+			return err
+		}
+
+		exists = len(result.Entries) > 0
+		return nil
+	})
+
+	return exists, err
+}
+
+// GetGroupBySID retrieves the DN of the group with the given objectSid, or an empty
+// string if there is none
+func (s *ldapSvc) GetGroupBySID(sid string) (string, error) {
+	dn := ""
+	err := s.withConnection(func(conn *ldap.Conn) error {
+		// Active Directory accepts the string form of a SID in filters
 		searchRequest := ldap.NewSearchRequest(
 			s.env.LDAPGroupBaseDN,
 			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-			fmt.Sprintf(s.env.LDAPGroupFilter, dn),
+			fmt.Sprintf("(objectSid=%s)", ldap.EscapeFilter(sid)),
 			[]string{"dn"},
 			nil,
 		)
 
 		result, err := conn.Search(searchRequest)
 		if err != nil {
+			if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+				return nil
+			}
 			return err
 		}
 
-		for _, entry := range result.Entries {
-			groups = append(groups, entry.DN)
+		if len(result.Entries) > 1 {
+			return ldap.NewError(ldap.LDAPResultAmbiguousResponse, nil)
 		}
 
+		if len(result.Entries) == 1 {
+			dn = result.Entries[0].DN
+		}
 		return nil
 	})
 
-	return groups, err
+	return dn, err
+}
+
+func (s *ldapSvc) PoolStats() PoolStats {
+	return s.pool.snapshot()
+}
+
+func (s *ldapSvc) Servers() []ServerStatus {
+	return s.servers.statuses()
 }
 
-// WithConnection handles connection setup, bind, and cleanup per operation
+func (s *ldapSvc) PurgeUser(username string) bool {
+	purged := false
+	if user, ok := s.users.peek(username); ok {
+		purged = s.groups.purge(dnKey(user.DN))
+	}
+	return s.users.purge(username) || purged
+}
+
+// withConnection runs an operation on a pooled connection. An operation failing because a
+// reused connection was dropped by the server is retried once on a checked connection.
 func (s *ldapSvc) withConnection(fn func(conn *ldap.Conn) error) error {
-	conn, err := ldap.DialURL(s.env.LDAPURL)
+	conn, err := s.pool.get(false)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		err := conn.Close()
+	err = s.run(conn, fn)
+	if !conn.reused || !isConnectionError(err) {
+		return err
+	}
+
+	s.logger.Debug("Retrying on a new LDAP connection", "error", err)
+	conn, err = s.pool.get(true)
+	if err != nil {
+		return err
+	}
+	return s.run(conn, fn)
+}
+
+// run runs an operation and returns the connection to the pool. Servers are quarantined
+// when a connection fails while fresh, idle connections may just have been dropped.
+func (s *ldapSvc) run(conn *pooledConn, fn func(conn *ldap.Conn) error) error {
+	err := fn(conn.Conn)
+	s.pool.put(conn, err)
+	if !conn.reused && isConnectionError(err) {
+		s.servers.fail(conn.server, err)
+	}
+	return err
+}
+
+// dial opens a connection bound with the service account to the first server answering,
+// quarantining the others
+func (s *ldapSvc) dial() (*ldap.Conn, string, error) {
+	candidates, err := s.servers.candidates()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var errs []error
+	for _, server := range candidates {
+		conn, err := s.connect(server)
+		if err == nil {
+			err = s.bind(conn, server)
+			if err != nil {
+				s.close(conn)
+				// the other servers would refuse the credentials as well
+				if !isConnectionError(err) {
+					return nil, "", err
+				}
+			}
+		}
 		if err != nil {
-			s.logger.Error("Failed to close LDAP connection", "error", err)
+			s.servers.fail(server, err)
+			errs = append(errs, fmt.Errorf("%s: %w", server, err))
+			continue
 		}
-	}()
+		s.servers.succeed(server)
+		return conn, server, nil
+	}
+	return nil, "", errors.Join(errs...)
+}
 
-	if err := conn.Bind(s.env.LDAPBindDN, s.env.LDAPBindPassword); err != nil {
-		return err
+// bind authenticates a connection as the service account
+func (s *ldapSvc) bind(conn *ldap.Conn, server string) error {
+	if s.env.LDAPBindMode == envsvc.LDAPBindModeGSSAPI {
+		return s.gssapiBind(conn, server)
+	}
+	return conn.Bind(s.env.LDAPBindDN, s.env.LDAPBindPassword)
+}
+
+// connect opens a connection to a server, upgraded with StartTLS when enabled
+func (s *ldapSvc) connect(server string) (*ldap.Conn, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := s.tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = u.Hostname()
 	}
 
-	return fn(conn)
+	conn, err := ldap.DialURL(server, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	// a realm may use an ldaps:// URL, already encrypted
+	if s.env.LDAPStartTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			s.close(conn)
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (s *ldapSvc) close(conn *ldap.Conn) {
+	if err := conn.Close(); err != nil {
+		s.logger.Error("Failed to close LDAP connection", "error", err)
+	}
 }
@@ -0,0 +1,135 @@
+package ldapsvc
+
+import (
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// matchingRuleInChain is the Active Directory LDAP_MATCHING_RULE_IN_CHAIN, matching the
+// groups an entry is a member of through any number of groups
+const matchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+// searchGroups returns the DNs of the groups of the group base DN matching filter
+func (s *ldapSvc) searchGroups(conn *ldap.Conn, filter string) ([]string, error) {
+	searchRequest := ldap.NewSearchRequest(
+		s.env.LDAPGroupBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{"dn"},
+		nil,
+	)
+
+	result, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.DN)
+	}
+	return groups, nil
+}
+
+// memberOf returns the groups of the group base DN listed in the memberOf attribute of an
+// entry
+func (s *ldapSvc) memberOf(conn *ldap.Conn, dn string) ([]string, error) {
+	searchRequest := ldap.NewSearchRequest(
+		dn,
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)",
+		[]string{s.env.LDAPMemberOfAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(searchRequest)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(result.Entries) == 0 {
+		return nil, nil
+	}
+
+	baseDN, err := ldap.ParseDN(s.env.LDAPGroupBaseDN)
+	if err != nil {
+		return nil, err
+	}
+	var groups []string
+	for _, group := range result.Entries[0].GetAttributeValues(s.env.LDAPMemberOfAttribute) {
+		groupDN, err := ldap.ParseDN(group)
+		if err != nil || !baseDN.AncestorOfFold(groupDN) {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// resolveNested walks the groups of dn breadth first, parentsOf returning the groups an
+// entry is a direct member of. Groups already found are not walked again, which stops
+// membership cycles.
+func (s *ldapSvc) resolveNested(
+	dn string,
+	parentsOf func(dn string) ([]string, error),
+) ([]string, error) {
+	// foundFrom maps the key of each entry walked to the key of the member it was found from
+	foundFrom := map[string]string{dnKey(dn): ""}
+	var groups []string
+	level := []string{dn}
+	for depth := 1; len(level) > 0; depth++ {
+		if depth > s.env.LDAPNestedGroupsMaxDepth {
+			s.logger.Warn(
+				"Ignoring groups nested deeper than the maximum depth",
+				"dn", dn,
+				"maxDepth", s.env.LDAPNestedGroupsMaxDepth,
+			)
+			break
+		}
+
+		var next []string
+		for _, member := range level {
+			parents, err := parentsOf(member)
+			if err != nil {
+				return nil, err
+			}
+			memberKey := dnKey(member)
+			for _, parent := range parents {
+				key := dnKey(parent)
+				if _, seen := foundFrom[key]; seen {
+					if isFoundFrom(foundFrom, memberKey, key) {
+						s.logger.Debug("Group membership cycle", "dn", dn, "group", parent)
+					}
+					continue
+				}
+				foundFrom[key] = memberKey
+				groups = append(groups, parent)
+				next = append(next, parent)
+			}
+		}
+		level = next
+	}
+	return groups, nil
+}
+
+// isFoundFrom reports whether key is the entry or one of the entries it was found from
+func isFoundFrom(foundFrom map[string]string, entry string, key string) bool {
+	for ; entry != ""; entry = foundFrom[entry] {
+		if entry == key {
+			return true
+		}
+	}
+	return false
+}
+
+// dnKey normalizes a DN to compare DNs differing in case or spacing
+func dnKey(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(dn)
+	}
+	return strings.ToLower(parsed.String())
+}
@@ -0,0 +1,132 @@
+package ldapsvc
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+)
+
+func TestResolveNested(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		// parents maps each entry to the groups it is a direct member of
+		parents map[string][]string
+		// failing is the entry whose lookup fails
+		failing string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:     "no groups",
+			maxDepth: 10,
+			parents:  map[string][]string{},
+			want:     nil,
+		},
+		{
+			name:     "groups are walked breadth first",
+			maxDepth: 10,
+			parents: map[string][]string{
+				"uid=alice": {"cn=dev", "cn=ops"},
+				"cn=dev":    {"cn=staff"},
+				"cn=ops":    {"cn=admins"},
+				"cn=admins": {"cn=root"},
+			},
+			want: []string{"cn=dev", "cn=ops", "cn=staff", "cn=admins", "cn=root"},
+		},
+		{
+			name:     "depth bounds the levels walked",
+			maxDepth: 2,
+			parents: map[string][]string{
+				"uid=alice": {"cn=dev"},
+				"cn=dev":    {"cn=staff"},
+				"cn=staff":  {"cn=everyone"},
+			},
+			want: []string{"cn=dev", "cn=staff"},
+		},
+		{
+			name:     "cycle stops",
+			maxDepth: 10,
+			parents: map[string][]string{
+				"uid=alice": {"cn=a"},
+				"cn=a":      {"cn=b"},
+				"cn=b":      {"cn=a"},
+			},
+			want: []string{"cn=a", "cn=b"},
+		},
+		{
+			name:     "group reached twice is returned once",
+			maxDepth: 10,
+			parents: map[string][]string{
+				"uid=alice": {"cn=dev", "cn=ops"},
+				"cn=dev":    {"cn=staff"},
+				"cn=ops":    {"CN=Staff"},
+			},
+			want: []string{"cn=dev", "cn=ops", "cn=staff"},
+		},
+		{
+			name:     "group listing the user is not returned",
+			maxDepth: 10,
+			parents: map[string][]string{
+				"uid=alice": {"cn=dev"},
+				"cn=dev":    {"UID=Alice"},
+			},
+			want: []string{"cn=dev"},
+		},
+		{
+			name:     "lookup error is returned",
+			maxDepth: 10,
+			parents: map[string][]string{
+				"uid=alice": {"cn=dev"},
+			},
+			failing: "cn=dev",
+			wantErr: errUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ldapSvc{
+				env:    envsvc.Env{LDAPNestedGroupsMaxDepth: tt.maxDepth},
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			parentsOf := func(dn string) ([]string, error) {
+				if dn == tt.failing {
+					return nil, errUnavailable
+				}
+				return tt.parents[dn], nil
+			}
+
+			got, err := s.resolveNested("uid=alice", parentsOf)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveNested error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("resolveNested = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDNKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{a: "cn=dev,dc=example,dc=com", b: "CN=Dev, DC=Example, DC=Com", same: true},
+		{a: "cn=dev,dc=example,dc=com", b: "cn=ops,dc=example,dc=com", same: false},
+		{a: "not a dn", b: "NOT A DN", same: true},
+	}
+
+	for _, tt := range tests {
+		if got := dnKey(tt.a) == dnKey(tt.b); got != tt.same {
+			t.Errorf("dnKey(%q) == dnKey(%q) is %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package ldapsvc
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// PoolStats describes the connections of the LDAP pool
+type PoolStats struct {
+	// MaxSize is the maximum number of open connections
+	MaxSize int
+	// Open is the number of open connections, idle or in use
+	Open int
+	// Idle is the number of connections waiting to be reused
+	Idle int
+	// InUse is the number of connections running an operation
+	InUse int
+	// Dials is the number of connections opened and bound
+	Dials uint64
+	// Reuses is the number of operations run on an idle connection
+	Reuses uint64
+	// Discarded is the number of connections closed after a failed check or a network error
+	Discarded uint64
+	// Expired is the number of idle connections closed after the idle timeout
+	Expired uint64
+	// Waits is the number of operations that waited for a connection, the pool being full
+	Waits uint64
+	// WaitDuration is the total time spent waiting for a connection
+	WaitDuration time.Duration
+}
+
+// pooledConn is a bound connection of the pool
+type pooledConn struct {
+	*ldap.Conn
+	// server is the URL of the server the connection is open to
+	server   string
+	lastUsed time.Time
+	reused   bool
+}
+
+// pool keeps bound connections for reuse. Connections idle for longer than checkAfter are
+// checked before reuse, and closed once idle for longer than idleTimeout.
+type pool struct {
+	dial        func() (*ldap.Conn, string, error)
+	idleTimeout time.Duration
+	checkAfter  time.Duration
+
+	// slots holds a token per running operation, idle connections are returned before the
+	// token is released so that dials only happen when every open connection is in use
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []*pooledConn
+	reaper *time.Timer
+	stats  PoolStats
+}
+
+// newPool returns a pool of at most size connections, negative durations disable the idle
+// timeout and the checks
+func newPool(
+	dial func() (*ldap.Conn, string, error),
+	size int,
+	idleTimeout time.Duration,
+	checkAfter time.Duration,
+) *pool {
+	p := &pool{
+		dial:        dial,
+		idleTimeout: idleTimeout,
+		checkAfter:  checkAfter,
+		slots:       make(chan struct{}, size),
+	}
+	p.stats.MaxSize = size
+	return p
+}
+
+// get returns an idle connection, or dials one when there is none. Idle connections are
+// always checked when verify is set.
+func (p *pool) get(verify bool) (*pooledConn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		start := time.Now()
+		p.slots <- struct{}{}
+		p.mu.Lock()
+		p.stats.Waits++
+		p.stats.WaitDuration += time.Since(start)
+		p.mu.Unlock()
+	}
+
+	for {
+		conn := p.popIdle()
+		if conn == nil {
+			break
+		}
+		if conn.IsClosing() || !p.alive(conn, verify) {
+			p.close(conn)
+			p.mu.Lock()
+			p.stats.Discarded++
+			p.mu.Unlock()
+			continue
+		}
+		conn.reused = true
+		p.mu.Lock()
+		p.stats.Reuses++
+		p.stats.InUse++
+		p.mu.Unlock()
+		return conn, nil
+	}
+
+	conn, server, err := p.dial()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	p.mu.Lock()
+	p.stats.Dials++
+	p.stats.Open++
+	p.stats.InUse++
+	p.mu.Unlock()
+	return &pooledConn{Conn: conn, server: server}, nil
+}
+
+// put returns a connection to the pool once an operation is done, it is closed when the
+// operation failed with a connection error
+func (p *pool) put(conn *pooledConn, err error) {
+	defer func() { <-p.slots }()
+
+	p.mu.Lock()
+	p.stats.InUse--
+	p.mu.Unlock()
+	if isConnectionError(err) || conn.IsClosing() {
+		p.close(conn)
+		p.mu.Lock()
+		p.stats.Discarded++
+		p.mu.Unlock()
+		return
+	}
+
+	conn.lastUsed = time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idle = append(p.idle, conn)
+	p.stats.Idle++
+	if p.idleTimeout >= 0 && p.reaper == nil {
+		p.reaper = time.AfterFunc(p.idleTimeout, p.reap)
+	}
+}
+
+// popIdle returns the most recently used idle connection, which is the least likely to
+// have been dropped by the server
+func (p *pool) popIdle() *pooledConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle) == 0 {
+		return nil
+	}
+	conn := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+	p.stats.Idle--
+	return conn
+}
+
+// alive checks a connection idle for longer than checkAfter, or any when verify is set,
+// by reading the root DSE
+func (p *pool) alive(conn *pooledConn, verify bool) bool {
+	if !verify && (p.checkAfter < 0 || time.Since(conn.lastUsed) < p.checkAfter) {
+		return true
+	}
+	_, err := conn.Search(ldap.NewSearchRequest(
+		"",
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 5, false,
+		"(objectClass=*)",
+		[]string{"1.1"},
+		nil,
+	))
+	return err == nil
+}
+
+// reap closes the connections idle for longer than the idle timeout, it runs while idle
+// connections remain
+func (p *pool) reap() {
+	p.mu.Lock()
+	var expired []*pooledConn
+	kept := p.idle[:0]
+	next := p.idleTimeout
+	for _, conn := range p.idle {
+		idleFor := time.Since(conn.lastUsed)
+		if idleFor >= p.idleTimeout {
+			expired = append(expired, conn)
+			continue
+		}
+		kept = append(kept, conn)
+		next = min(next, p.idleTimeout-idleFor)
+	}
+	clear(p.idle[len(kept):])
+	p.idle = kept
+	p.stats.Idle = len(kept)
+	p.stats.Expired += uint64(len(expired))
+	if len(kept) > 0 {
+		p.reaper = time.AfterFunc(next, p.reap)
+	} else {
+		p.reaper = nil
+	}
+	p.mu.Unlock()
+
+	for _, conn := range expired {
+		p.close(conn)
+	}
+}
+
+func (p *pool) close(conn *pooledConn) {
+	// the error only reports an already closed connection
+	_ = conn.Close()
+	p.mu.Lock()
+	p.stats.Open--
+	p.mu.Unlock()
+}
+
+// snapshot returns the current statistics of the pool
+func (p *pool) snapshot() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// isConnectionError reports whether err means the connection is no longer usable
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if ldap.IsErrorAnyOf(err, ldap.ErrorNetwork, ldap.LDAPResultUnavailable) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,215 @@
+package ldapsvc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// pipeDial returns a dial function opening connections to a peer discarding requests
+func pipeDial(t *testing.T) func() (*ldap.Conn, string, error) {
+	return func() (*ldap.Conn, string, error) {
+		client, server := net.Pipe()
+		go func() { _, _ = io.Copy(io.Discard, server) }()
+		t.Cleanup(func() { _ = server.Close() })
+		conn := ldap.NewConn(client, false)
+		conn.Start()
+		return conn, "ldap://pipe", nil
+	}
+}
+
+// waitFor polls condition until it holds or a second elapsed
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPoolGetPut(t *testing.T) {
+	tests := []struct {
+		name string
+		// putErr is the error of the operation run on the first connection
+		putErr error
+		// closed closes the first connection before it is returned
+		closed bool
+		want   PoolStats
+	}{
+		{
+			name: "idle connection is reused",
+			want: PoolStats{MaxSize: 2, Open: 1, InUse: 1, Dials: 1, Reuses: 1},
+		},
+		{
+			name:   "connection failing with a network error is discarded",
+			putErr: ldap.NewError(ldap.ErrorNetwork, errors.New("reset")),
+			want:   PoolStats{MaxSize: 2, Open: 1, InUse: 1, Dials: 2, Discarded: 1},
+		},
+		{
+			name:   "connection failing with an LDAP result is kept",
+			putErr: ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("no such object")),
+			want:   PoolStats{MaxSize: 2, Open: 1, InUse: 1, Dials: 1, Reuses: 1},
+		},
+		{
+			name:   "closed connection is discarded",
+			closed: true,
+			want:   PoolStats{MaxSize: 2, Open: 1, InUse: 1, Dials: 2, Discarded: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPool(pipeDial(t), 2, -1, -1)
+
+			conn, err := p.get(false)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if tt.closed {
+				_ = conn.Close()
+			}
+			p.put(conn, tt.putErr)
+
+			conn, err = p.get(false)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			defer p.put(conn, nil)
+
+			if got := p.snapshot(); got != tt.want {
+				t.Errorf("stats = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoolWaitsForFreeSlot(t *testing.T) {
+	p := newPool(pipeDial(t), 1, -1, -1)
+
+	first, err := p.get(false)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	done := make(chan *pooledConn)
+	go func() {
+		conn, err := p.get(false)
+		if err != nil {
+			t.Errorf("get: %v", err)
+		}
+		done <- conn
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("get returned while the pool was full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	p.put(first, nil)
+	second := <-done
+	if second != first {
+		t.Error("the waiting operation did not reuse the returned connection")
+	}
+	p.put(second, nil)
+
+	if stats := p.snapshot(); stats.Waits != 1 || stats.Dials != 1 {
+		t.Errorf("stats = %+v, want a single wait and dial", stats)
+	}
+}
+
+func TestPoolDialError(t *testing.T) {
+	dialErr := errors.New("unreachable")
+	p := newPool(func() (*ldap.Conn, string, error) {
+		return nil, "", dialErr
+	}, 1, -1, -1)
+
+	for range 2 {
+		// a failed dial releases its slot, the second get would block otherwise
+		if _, err := p.get(false); !errors.Is(err, dialErr) {
+			t.Fatalf("get error = %v, want %v", err, dialErr)
+		}
+	}
+	if stats := p.snapshot(); stats.Open != 0 || stats.InUse != 0 || stats.Dials != 0 {
+		t.Errorf("stats = %+v, want no connection", stats)
+	}
+}
+
+func TestPoolReapsIdleConnections(t *testing.T) {
+	p := newPool(pipeDial(t), 2, 20*time.Millisecond, -1)
+
+	first, err := p.get(false)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	second, err := p.get(false)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	p.put(first, nil)
+	p.put(second, nil)
+
+	// expired connections are closed once the reaper released the lock
+	waitFor(t, func() bool { return p.snapshot().Open == 0 })
+	stats := p.snapshot()
+	if stats.Expired != 2 || stats.Idle != 0 {
+		t.Errorf("stats = %+v, want two expired connections", stats)
+	}
+	if !first.IsClosing() || !second.IsClosing() {
+		t.Error("expired connections are not closed")
+	}
+
+	p.mu.Lock()
+	reaper := p.reaper
+	p.mu.Unlock()
+	if reaper != nil {
+		t.Error("the reaper keeps running without idle connections")
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{
+			name: "network",
+			err:  ldap.NewError(ldap.ErrorNetwork, errors.New("reset")),
+			want: true,
+		},
+		{
+			name: "unavailable",
+			err:  ldap.NewError(ldap.LDAPResultUnavailable, errors.New("unavailable")),
+			want: true,
+		},
+		{
+			name: "no such object",
+			err:  ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("missing")),
+			want: false,
+		},
+		{
+			name: "wrapped net error",
+			err:  fmt.Errorf("search: %w", &net.OpError{Op: "read", Err: errors.New("reset")}),
+			want: true,
+		},
+		{name: "other", err: errors.New("invalid filter"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnectionError(tt.err); got != tt.want {
+				t.Errorf("isConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
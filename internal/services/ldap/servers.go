@@ -0,0 +1,188 @@
+package ldapsvc
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// srvRefreshInterval is how long discovered servers are used before the SRV records are
+// resolved again
+const srvRefreshInterval = 5 * time.Minute
+
+// ServerStatus describes the health of an LDAP server
+type ServerStatus struct {
+	URL string
+	// Failures is the number of consecutive failures
+	Failures int
+	// QuarantinedUntil is when a failing server is tried first again, zero for a healthy one
+	QuarantinedUntil time.Time
+}
+
+type serverHealth struct {
+	failures         int
+	quarantinedUntil time.Time
+}
+
+// servers orders the LDAP servers to try, healthy ones first. The servers come from the
+// configured URLs or from the SRV records of a domain.
+type servers struct {
+	urls       []string
+	srvDomain  string
+	srvService string
+	quarantine time.Duration
+	logger     *slog.Logger
+
+	mu         sync.Mutex
+	resolved   []string
+	resolvedAt time.Time
+	health     map[string]*serverHealth
+}
+
+func newServers(
+	urls []string,
+	srvDomain string,
+	srvService string,
+	quarantine time.Duration,
+	logger *slog.Logger,
+) *servers {
+	return &servers{
+		urls:       urls,
+		srvDomain:  srvDomain,
+		srvService: srvService,
+		quarantine: quarantine,
+		logger:     logger,
+		health:     map[string]*serverHealth{},
+	}
+}
+
+// candidates returns the servers to try in order: the healthy ones in configured or SRV
+// priority order, then the quarantined ones, soonest released first, in case every other
+// server fails too
+func (s *servers) candidates() ([]string, error) {
+	urls, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var healthy, quarantined []string
+	for _, u := range urls {
+		if h := s.health[u]; h != nil && now.Before(h.quarantinedUntil) {
+			quarantined = append(quarantined, u)
+		} else {
+			healthy = append(healthy, u)
+		}
+	}
+	slices.SortStableFunc(quarantined, func(a, b string) int {
+		return s.health[a].quarantinedUntil.Compare(s.health[b].quarantinedUntil)
+	})
+	return append(healthy, quarantined...), nil
+}
+
+// list returns the configured servers, or the ones discovered from the SRV records
+func (s *servers) list() ([]string, error) {
+	if s.srvDomain == "" {
+		return s.urls, nil
+	}
+
+	s.mu.Lock()
+	if s.resolved != nil && time.Since(s.resolvedAt) < srvRefreshInterval {
+		defer s.mu.Unlock()
+		return s.resolved, nil
+	}
+	s.mu.Unlock()
+
+	// records are sorted by priority and randomized by weight
+	_, records, err := net.LookupSRV(s.srvService, "tcp", s.srvDomain)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		if s.resolved != nil {
+			s.logger.Warn("Failed to resolve LDAP SRV records, using the previous servers",
+				"domain", s.srvDomain,
+				"error", err,
+			)
+			return s.resolved, nil
+		}
+		return nil, ldap.NewError(ldap.ErrorNetwork, fmt.Errorf(
+			"failed to resolve _%s._tcp.%s: %w", s.srvService, s.srvDomain, err,
+		))
+	}
+
+	urls := make([]string, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		urls = append(
+			urls,
+			s.srvService+"://"+net.JoinHostPort(host, strconv.Itoa(int(record.Port))),
+		)
+	}
+	if len(urls) == 0 {
+		return nil, ldap.NewError(ldap.ErrorNetwork, fmt.Errorf(
+			"no server found in _%s._tcp.%s", s.srvService, s.srvDomain,
+		))
+	}
+	s.resolved = urls
+	s.resolvedAt = time.Now()
+	return urls, nil
+}
+
+// fail quarantines a server
+func (s *servers) fail(u string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.health[u]
+	if h == nil {
+		h = &serverHealth{}
+		s.health[u] = h
+	}
+	h.failures++
+	h.quarantinedUntil = time.Now().Add(s.quarantine)
+	s.logger.Warn("Quarantining LDAP server",
+		"server", u,
+		"failures", h.failures,
+		"until", h.quarantinedUntil,
+		"error", err,
+	)
+}
+
+// succeed clears the failures of a server
+func (s *servers) succeed(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.health[u]; ok {
+		delete(s.health, u)
+		s.logger.Info("LDAP server recovered", "server", u)
+	}
+}
+
+// statuses returns the health of every known server
+func (s *servers) statuses() []ServerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	urls := s.urls
+	if s.srvDomain != "" {
+		urls = s.resolved
+	}
+
+	statuses := make([]ServerStatus, 0, len(urls))
+	for _, u := range urls {
+		status := ServerStatus{URL: u}
+		if h := s.health[u]; h != nil {
+			status.Failures = h.failures
+			status.QuarantinedUntil = h.quarantinedUntil
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
@@ -0,0 +1,126 @@
+package pacsvc
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	ldapsvc "github.com/froz42/kerbernetes/internal/services/ldap"
+	"github.com/samber/do"
+	"sigs.k8s.io/yaml"
+)
+
+type PACSvc interface {
+	// ResolveGroups maps the group SIDs of a Kerberos PAC to group DNs.
+	// SIDs that do not resolve to a group are ignored.
+	ResolveGroups(sids []string) ([]string, error)
+}
+
+type pacSvc struct {
+	env     envsvc.Env
+	ldapSvc ldapsvc.LDAPSvc
+	logger  *slog.Logger
+
+	// mapping holds the static SID to DN mapping
+	mapping map[string]string
+	// resolved caches SIDs looked up in LDAP, an empty DN means the SID is not a group
+	resolved sync.Map
+}
+
+func NewProvider() func(i *do.Injector) (PACSvc, error) {
+	return func(i *do.Injector) (PACSvc, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[ldapsvc.LDAPSvc](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+func New(
+	configService envsvc.EnvSvc,
+	ldapSvc ldapsvc.LDAPSvc,
+	logger *slog.Logger,
+) (PACSvc, error) {
+	env := configService.GetEnv()
+	svc := &pacSvc{
+		env:     env,
+		ldapSvc: ldapSvc,
+		logger:  logger.With("service", "pac"),
+		mapping: map[string]string{},
+	}
+
+	if env.PACEnabled && env.PACSIDMappingPath != "" {
+		mapping, err := loadMapping(env.PACSIDMappingPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SID mapping: %w", err)
+		}
+		svc.mapping = mapping
+		svc.logger.Info(
+			"Loaded SID mapping",
+			"path", env.PACSIDMappingPath,
+			"count", len(mapping),
+		)
+	}
+
+	return svc, nil
+}
+
+// loadMapping reads a YAML or JSON object mapping group SIDs to group DNs
+func loadMapping(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mapping := map[string]string{}
+	if err := yaml.Unmarshal(raw, &mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// ResolveGroups maps the group SIDs of a Kerberos PAC to group DNs.
+// The static mapping is used first, remaining SIDs are looked up once in LDAP when it
+// is enabled and the result is kept for the lifetime of the process.
+func (s *pacSvc) ResolveGroups(sids []string) ([]string, error) {
+	groups := []string{}
+	seen := map[string]bool{}
+	for _, sid := range sids {
+		dn, err := s.resolve(sid)
+		if err != nil {
+			return nil, err
+		}
+		if dn == "" || seen[dn] {
+			continue
+		}
+		seen[dn] = true
+		groups = append(groups, dn)
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
+
+func (s *pacSvc) resolve(sid string) (string, error) {
+	if dn, ok := s.mapping[sid]; ok {
+		return dn, nil
+	}
+	if !s.env.LDAPEnabled {
+		return "", nil
+	}
+	if dn, ok := s.resolved.Load(sid); ok {
+		return dn.(string), nil
+	}
+
+	dn, err := s.ldapSvc.GetGroupBySID(sid)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up SID %s: %w", sid, err)
+	}
+	if dn == "" {
+		s.logger.Debug("SID does not match any group", "sid", sid)
+	}
+	s.resolved.Store(sid, dn)
+	return dn, nil
+}
@@ -0,0 +1,123 @@
+package proxysvc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// groupCache caches the groups of the principals using the proxy. Groups are refreshed
+// in the background while the principal keeps sending requests so that requests rarely
+// wait for a group provider, and dropped once the principal is idle for a TTL.
+type groupCache struct {
+	ttl     time.Duration
+	resolve func(ctx context.Context, principal string) ([]string, error)
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	entries map[string]*groupEntry
+}
+
+type groupEntry struct {
+	groups   []string
+	resolved time.Time
+	used     time.Time
+}
+
+func newGroupCache(
+	ttl time.Duration,
+	resolve func(ctx context.Context, principal string) ([]string, error),
+	logger *slog.Logger,
+) *groupCache {
+	return &groupCache{
+		ttl:     ttl,
+		resolve: resolve,
+		logger:  logger,
+		entries: make(map[string]*groupEntry),
+	}
+}
+
+// get returns the groups of a principal, resolving them when missing or expired.
+func (c *groupCache) get(ctx context.Context, principal string) ([]string, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[principal]
+	if ok && now.Sub(entry.resolved) < c.ttl {
+		entry.used = now
+		c.mu.Unlock()
+		return entry.groups, nil
+	}
+	c.mu.Unlock()
+
+	groups, err := c.resolve(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[principal] = &groupEntry{groups: groups, resolved: now, used: now}
+	c.mu.Unlock()
+	return groups, nil
+}
+
+// run refreshes the groups in use every half TTL until the context is cancelled.
+func (c *groupCache) run(ctx context.Context) {
+	ticker := time.NewTicker(c.ttl / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.refresh(ctx)
+		}
+	}
+}
+
+func (c *groupCache) refresh(ctx context.Context) {
+	now := time.Now()
+
+	var principals []string
+	c.mu.Lock()
+	for principal, entry := range c.entries {
+		if now.Sub(entry.used) >= c.ttl {
+			delete(c.entries, principal)
+			continue
+		}
+		if now.Sub(entry.resolved) >= c.ttl/2 {
+			principals = append(principals, principal)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, principal := range principals {
+		groups, err := c.resolve(ctx, principal)
+		var statusErr huma.StatusError
+		if errors.As(err, &statusErr) && statusErr.GetStatus() == http.StatusForbidden {
+			// a disabled account or a denied realm must not keep its groups until they expire
+			c.logger.Warn("Dropping groups of forbidden principal", "principal", principal)
+			c.mu.Lock()
+			delete(c.entries, principal)
+			c.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			// the entry expires and the next request reports the error
+			c.logger.Warn("Failed to refresh groups", "principal", principal, "error", err)
+			continue
+		}
+
+		c.mu.Lock()
+		if entry, ok := c.entries[principal]; ok {
+			entry.groups = groups
+			entry.resolved = time.Now()
+		}
+		c.mu.Unlock()
+	}
+}
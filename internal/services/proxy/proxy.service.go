@@ -0,0 +1,256 @@
+package proxysvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/froz42/kerbernetes/internal/middlewares"
+	"github.com/froz42/kerbernetes/internal/security"
+	authsvc "github.com/froz42/kerbernetes/internal/services/auth"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	keytabsvc "github.com/froz42/kerbernetes/internal/services/keytab"
+	"github.com/samber/do"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/rest"
+)
+
+// impersonatePrefix is the prefix of the impersonation headers, which clients must not set
+const impersonatePrefix = "Impersonate-"
+
+type ProxySvc interface {
+	// Start serves the proxy until the context is cancelled, it returns immediately when
+	// the proxy is disabled
+	Start(ctx context.Context) error
+}
+
+type proxySvc struct {
+	env       envsvc.Env
+	k8sSvc    k8ssvc.K8sService
+	authSvc   authsvc.AuthService
+	keytabSvc keytabsvc.KeytabSvc
+	logger    *slog.Logger
+
+	groups *groupCache
+}
+
+func NewProvider() func(i *do.Injector) (ProxySvc, error) {
+	return func(i *do.Injector) (ProxySvc, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[k8ssvc.K8sService](i),
+			do.MustInvoke[authsvc.AuthService](i),
+			do.MustInvoke[keytabsvc.KeytabSvc](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+func New(
+	envSvc envsvc.EnvSvc,
+	k8sSvc k8ssvc.K8sService,
+	authSvc authsvc.AuthService,
+	keytabSvc keytabsvc.KeytabSvc,
+	logger *slog.Logger,
+) (ProxySvc, error) {
+	env := envSvc.GetEnv()
+	svc := &proxySvc{
+		env:       env,
+		k8sSvc:    k8sSvc,
+		authSvc:   authSvc,
+		keytabSvc: keytabSvc,
+		logger:    logger.With("service", "proxy"),
+	}
+	svc.groups = newGroupCache(
+		time.Duration(env.ProxyGroupCacheTTL)*time.Second,
+		svc.resolveGroups,
+		svc.logger,
+	)
+	return svc, nil
+}
+
+func (svc *proxySvc) Start(ctx context.Context) error {
+	if !svc.env.ProxyEnabled {
+		return nil
+	}
+
+	handler, err := svc.handler()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr: fmt.Sprintf(":%d", svc.env.ProxyPort),
+		Handler: middlewares.SPNEGOHandler(
+			svc.logger,
+			svc.keytabSvc.Keytab,
+			svc.env.PACEnabled,
+			handler,
+		),
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+	go svc.groups.run(ctx)
+
+	if svc.env.ProxyCertPath == "" {
+		svc.logger.Warn("Started proxy server without TLS", "port", svc.env.ProxyPort)
+		err = server.ListenAndServe()
+	} else {
+		svc.logger.Info("Started proxy server", "port", svc.env.ProxyPort)
+		err = server.ListenAndServeTLS(svc.env.ProxyCertPath, svc.env.ProxyKeyPath)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// handler returns the reverse proxy to the API server using the kerbernetes credentials.
+func (svc *proxySvc) handler() (http.Handler, error) {
+	restConfig := svc.k8sSvc.GetRestConfig()
+	target, _, err := rest.DefaultServerUrlFor(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse API server URL: %w", err)
+	}
+
+	transport, err := rest.TransportFor(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API server transport: %w", err)
+	}
+	// exec, attach and port-forward upgrade the connection, which HTTP/2 does not support
+	upgradeConfig := rest.CopyConfig(restConfig)
+	upgradeConfig.NextProtos = []string{"http/1.1"}
+	upgradeTransport, err := rest.TransportFor(upgradeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API server upgrade transport: %w", err)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			impersonate(r.In.Context(), r.Out)
+		},
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if httpstream.IsUpgradeRequest(r) {
+				return upgradeTransport.RoundTrip(r)
+			}
+			return transport.RoundTrip(r)
+		}),
+		// watches stream their events
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			svc.logger.Error("Failed to proxy request", "path", r.URL.Path, "error", err)
+			http.Error(w, "failed to reach the API server", http.StatusBadGateway)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := security.GetPrincipalFromContext(r.Context())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if security.IsReservedIdentity(principal) {
+			http.Error(w, "principal maps to a reserved identity", http.StatusForbidden)
+			return
+		}
+
+		groups, err := svc.principalGroups(r.Context(), principal)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), impersonationContextKey, impersonation{
+			user:   principal,
+			groups: groups,
+		})
+		proxy.ServeHTTP(w, r.WithContext(ctx))
+	}), nil
+}
+
+// principalGroups returns the groups of a principal. Groups carried by the ticket PAC
+// change with every ticket and are not cached.
+func (svc *proxySvc) principalGroups(ctx context.Context, principal string) ([]string, error) {
+	if _, ok := security.GetGroupSIDsFromContext(ctx); svc.env.PACEnabled && ok {
+		return svc.resolveGroups(ctx, principal)
+	}
+	return svc.groups.get(ctx, principal)
+}
+
+func (svc *proxySvc) resolveGroups(ctx context.Context, principal string) ([]string, error) {
+	// a ticket stays valid after its directory account is disabled, the check also runs
+	// on every background refresh
+	if err := svc.authSvc.CheckAccount(principal); err != nil {
+		return nil, err
+	}
+	groups, _, err := svc.authSvc.ResolveGroups(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if security.IsReservedIdentity(group) {
+			svc.logger.Warn("Dropping reserved group", "principal", principal, "group", group)
+			continue
+		}
+		allowed = append(allowed, group)
+	}
+	return allowed, nil
+}
+
+type contextKey string
+
+const impersonationContextKey contextKey = "impersonation"
+
+type impersonation struct {
+	user   string
+	groups []string
+}
+
+// impersonate replaces the client credentials of an outgoing request with the
+// impersonation headers of the authenticated principal.
+func impersonate(ctx context.Context, out *http.Request) {
+	// the Negotiate header would otherwise be sent instead of the kerbernetes credentials
+	out.Header.Del("Authorization")
+	for name := range out.Header {
+		if strings.HasPrefix(name, impersonatePrefix) {
+			out.Header.Del(name)
+		}
+	}
+
+	identity, ok := ctx.Value(impersonationContextKey).(impersonation)
+	if !ok {
+		return
+	}
+	out.Header.Set("Impersonate-User", identity.user)
+	for _, group := range identity.groups {
+		out.Header.Add("Impersonate-Group", group)
+	}
+}
+
+// writeError writes an error returned by the auth service, keeping its status.
+func writeError(w http.ResponseWriter, err error) {
+	var statusErr huma.StatusError
+	if errors.As(err, &statusErr) {
+		http.Error(w, statusErr.Error(), statusErr.GetStatus())
+		return
+	}
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
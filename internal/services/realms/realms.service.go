@@ -0,0 +1,221 @@
+package realmssvc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	keytabsvc "github.com/froz42/kerbernetes/internal/services/keytab"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/samber/do"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// ErrRealmDenied is returned for principals of a realm that is not allowed
+var ErrRealmDenied = errors.New("realm is not allowed")
+
+// ErrNoDefaultRealm is returned when DEFAULT_REALM is unset and no keytab is loaded
+var ErrNoDefaultRealm = errors.New("no default realm configured nor keytab loaded")
+
+// Policy is the realm policy file.
+type Policy struct {
+	// Allow lists the accepted realms, every realm is accepted when empty
+	Allow []string `json:"allow"`
+	// Deny lists the rejected realms, it takes precedence over Allow
+	Deny []string `json:"deny"`
+	// Realms holds the settings of specific realms
+	Realms map[string]Realm `json:"realms"`
+}
+
+// Realm holds the settings applied to the principals of a realm.
+type Realm struct {
+	// Namespace of the service accounts, the kerbernetes namespace when empty
+	Namespace string `json:"namespace"`
+	// Prefix is prepended to the service account names
+	Prefix string `json:"prefix"`
+	// LDAP overrides the LDAP settings used to resolve groups
+	LDAP *LDAPSettings `json:"ldap"`
+	// SharedDirectory resolves the groups of the realm like those of the default realm,
+	// from the default LDAP settings and the usernames of the static groups, for realms
+	// whose users are the same people as the default realm ones
+	SharedDirectory bool `json:"sharedDirectory"`
+}
+
+// LDAPSettings overrides the LDAP environment settings, empty fields are inherited.
+type LDAPSettings struct {
+	URL              string `json:"url"`
+	BindDN           string `json:"bindDN"`
+	BindPassword     string `json:"bindPassword"`
+	BindPasswordFile string `json:"bindPasswordFile"`
+	UserBaseDN       string `json:"userBaseDN"`
+	UserFilter       string `json:"userFilter"`
+	GroupBaseDN      string `json:"groupBaseDN"`
+	GroupFilter      string `json:"groupFilter"`
+}
+
+type RealmsSvc interface {
+	// Resolve returns the settings of a realm, or ErrRealmDenied.
+	// Principals without realm get the default settings.
+	Resolve(realm string) (Realm, error)
+
+	// LDAPRealms returns the realms overriding the LDAP settings
+	LDAPRealms() map[string]LDAPSettings
+
+	// SharesDefaultDirectory reports whether the users of a realm are resolved with the
+	// default group settings: principals without realm, of the default realm or of a
+	// realm with SharedDirectory. Users of other realms are kept apart from them.
+	SharesDefaultDirectory(realm string) (bool, error)
+}
+
+type realmsSvc struct {
+	policy Policy
+	// defaultRealm is DEFAULT_REALM, the realm of the keytab when empty
+	defaultRealm string
+	keytab       func() *keytab.Keytab
+	logger       *slog.Logger
+}
+
+func NewProvider() func(i *do.Injector) (RealmsSvc, error) {
+	return func(i *do.Injector) (RealmsSvc, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[keytabsvc.KeytabSvc](i).Keytab,
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+// New returns the realm policy, getKeytab returns the keytab whose realm is the default
+// one when DEFAULT_REALM is unset
+func New(
+	configService envsvc.EnvSvc,
+	getKeytab func() *keytab.Keytab,
+	logger *slog.Logger,
+) (RealmsSvc, error) {
+	env := configService.GetEnv()
+	svc := &realmsSvc{
+		defaultRealm: env.DefaultRealm,
+		keytab:       getKeytab,
+		logger:       logger.With("service", "realms"),
+	}
+
+	if env.RealmsConfigPath == "" {
+		return svc, nil
+	}
+
+	policy, err := loadPolicy(env.RealmsConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load realm policy: %w", err)
+	}
+	svc.policy = policy
+	svc.logger.Info(
+		"Loaded realm policy",
+		"allow", policy.Allow,
+		"deny", policy.Deny,
+		"realms", len(policy.Realms),
+	)
+	return svc, nil
+}
+
+// loadPolicy reads and validates a YAML or JSON realm policy
+func loadPolicy(path string) (Policy, error) {
+	var policy Policy
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	if err := yaml.UnmarshalStrict(raw, &policy); err != nil {
+		return policy, err
+	}
+
+	for name, realm := range policy.Realms {
+		if realm.Namespace != "" {
+			if errs := validation.IsDNS1123Label(realm.Namespace); len(errs) > 0 {
+				return policy, fmt.Errorf(
+					"realm %s: invalid namespace %q: %s",
+					name,
+					realm.Namespace,
+					strings.Join(errs, ", "),
+				)
+			}
+		}
+		if realm.LDAP != nil && realm.LDAP.BindPasswordFile != "" {
+			password, err := os.ReadFile(realm.LDAP.BindPasswordFile)
+			if err != nil {
+				return policy, fmt.Errorf("realm %s: failed to read bind password: %w", name, err)
+			}
+			realm.LDAP.BindPassword = strings.TrimSpace(string(password))
+		}
+	}
+	return policy, nil
+}
+
+// Resolve returns the settings of a realm, or ErrRealmDenied.
+// Principals without realm get the default settings.
+func (s *realmsSvc) Resolve(realm string) (Realm, error) {
+	if realm == "" {
+		return Realm{}, nil
+	}
+	if slices.Contains(s.policy.Deny, realm) {
+		return Realm{}, ErrRealmDenied
+	}
+	if len(s.policy.Allow) > 0 && !slices.Contains(s.policy.Allow, realm) {
+		return Realm{}, ErrRealmDenied
+	}
+	return s.policy.Realms[realm], nil
+}
+
+// LDAPRealms returns the realms overriding the LDAP settings.
+func (s *realmsSvc) LDAPRealms() map[string]LDAPSettings {
+	realms := map[string]LDAPSettings{}
+	for name, realm := range s.policy.Realms {
+		if realm.LDAP != nil {
+			realms[name] = *realm.LDAP
+		}
+	}
+	return realms
+}
+
+// SharesDefaultDirectory reports whether the users of a realm are resolved with the default
+// group settings.
+func (s *realmsSvc) SharesDefaultDirectory(realm string) (bool, error) {
+	if realm == "" || s.policy.Realms[realm].SharedDirectory {
+		return true, nil
+	}
+	defaultRealm := s.defaultRealm
+	if defaultRealm == "" {
+		// the service principal lives in the realm of the users it authenticates
+		kt := s.keytab()
+		if kt == nil || len(kt.Entries) == 0 {
+			return false, ErrNoDefaultRealm
+		}
+		defaultRealm = kt.Entries[0].Principal.Realm
+	}
+	return realm == defaultRealm, nil
+}
+
+// Apply returns env with the non-empty LDAP settings overridden.
+func (l LDAPSettings) Apply(env envsvc.Env) envsvc.Env {
+	override := func(dst *string, value string) {
+		if value != "" {
+			*dst = value
+		}
+	}
+	if l.URL != "" {
+		// the realm servers replace the discovered ones
+		env.LDAPURL = l.URL
+		env.LDAPSRVDomain = ""
+	}
+	override(&env.LDAPBindDN, l.BindDN)
+	override(&env.LDAPBindPassword, l.BindPassword)
+	override(&env.LDAPUserBaseDN, l.UserBaseDN)
+	override(&env.LDAPUserFilter, l.UserFilter)
+	override(&env.LDAPGroupBaseDN, l.GroupBaseDN)
+	override(&env.LDAPGroupFilter, l.GroupFilter)
+	return env
+}
@@ -0,0 +1,516 @@
+package reconcilersvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	serviceaccountssvc "github.com/froz42/kerbernetes/internal/services/k8s/serviceaccounts"
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func (s *reconcilerService) reconcileClusterAndRoleBindings(
+	ctx context.Context,
+	account types.NamespacedName,
+	ldapGroupBindings []*v1.LdapGroupBinding,
+) error {
+	s.logger.Info(
+		"Starting reconciliation of ClusterRoleBindings and RoleBindings for ServiceAccount",
+		"serviceAccount",
+		account,
+	)
+
+	// ------------------------------
+	// 1. Retrieve current state
+	// ------------------------------
+	clusterRoleBindingsMap, err := s.getExistingClusterRoleBindings(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	roleBindingsMap, err := s.getExistingRoleBindings(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	// ------------------------------
+	// 2. Ensure desired bindings exist
+	// ------------------------------
+	desiredClusterRoleBindings, desiredRoleBindings, err := s.collectBindings(
+		ldapGroupBindings,
+		func(roleName string, ldapGroupBindingName string) string {
+			return s.serviceAccountsSvc.BindingName(account, roleName, ldapGroupBindingName)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	for name, desired := range desiredClusterRoleBindings {
+		err = s.ensureClusterRoleBinding(
+			ctx,
+			account,
+			desired.ldapGroupBinding,
+			desired.item,
+			name,
+			clusterRoleBindingsMap,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, desired := range desiredRoleBindings {
+		err = s.ensureRoleBinding(
+			ctx,
+			account,
+			desired.ldapGroupBinding,
+			desired.item,
+			desired.namespace,
+			desired.name,
+			roleBindingsMap,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// ------------------------------
+	// 3. Remove bindings no longer needed
+	// ------------------------------
+	err = s.removeUnusedClusterRoleBindings(ctx, account, clusterRoleBindingsMap)
+	if err != nil {
+		return err
+	}
+
+	err = s.removeUnusedRoleBindings(ctx, account, roleBindingsMap)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info(
+		"Completed reconciliation of ClusterRoleBindings and RoleBindings",
+		"serviceAccount", account,
+		"remainingClusterRoleBindings", len(clusterRoleBindingsMap),
+		"remainingRoleBindings", len(roleBindingsMap),
+	)
+	return nil
+}
+
+//
+// -------- Helper functions --------
+//
+
+// desiredBinding is a binding granted by an LdapGroupBinding item
+type desiredBinding struct {
+	ldapGroupBinding string
+	item             v1.LdapGroupBindingItem
+	// namespace is empty for ClusterRoleBindings
+	namespace string
+	name      string
+}
+
+// roleRef returns the role reference of the binding
+func (d desiredBinding) roleRef() rbacv1.RoleRef {
+	return rbacv1.RoleRef{APIGroup: d.item.ApiGroup, Kind: d.item.Kind, Name: d.item.Name}
+}
+
+// errInvalidNamespaceSelector is returned for items whose namespace selector can not be
+// parsed, which the admission webhook only rejects once enabled
+var errInvalidNamespaceSelector = errors.New("invalid namespace selector")
+
+// collectBindings returns the ClusterRoleBindings by name and the RoleBindings by
+// roleBindingKey granted by the items of ldapGroupBindings, named by bindingName. Items
+// granting the same binding are applied once, and an item granting another role under a
+// binding name already granted, such as a Role and a ClusterRole of the same name in a
+// namespace, is skipped in favor of the first one. Items with an invalid namespace selector
+// are skipped, and reported in the LdapGroupBinding status.
+func (s *reconcilerService) collectBindings(
+	ldapGroupBindings []*v1.LdapGroupBinding,
+	bindingName func(roleName string, ldapGroupBindingName string) string,
+) (map[string]desiredBinding, map[string]desiredBinding, error) {
+	clusterRoleBindings := make(map[string]desiredBinding)
+	roleBindings := make(map[string]desiredBinding)
+
+	for _, ldapGroupBinding := range ldapGroupBindings {
+		for _, item := range ldapGroupBinding.Spec.Bindings {
+			desired := desiredBinding{
+				ldapGroupBinding: ldapGroupBinding.Name,
+				item:             item,
+				name:             bindingName(item.Name, ldapGroupBinding.Name),
+			}
+
+			switch {
+			// namespaced items, including ClusterRoles, are granted through RoleBindings
+			case item.IsNamespaced() && (item.Kind == "ClusterRole" || item.Kind == "Role"):
+				namespaces, err := s.targetNamespaces(item)
+				if errors.Is(err, errInvalidNamespaceSelector) {
+					s.logger.Warn(
+						"Skipping binding item with an invalid namespace selector",
+						"ldapGroupBinding", ldapGroupBinding.Name,
+						"kind", item.Kind,
+						"name", item.Name,
+						"error", err,
+					)
+					continue
+				}
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, namespace := range namespaces {
+					desired.namespace = namespace
+					key := roleBindingKey(namespace, desired.name)
+					if previous, exists := roleBindings[key]; exists {
+						if previous.roleRef() != desired.roleRef() {
+							s.logger.Warn(
+								"Skipping binding item conflicting with another one",
+								"ldapGroupBinding", ldapGroupBinding.Name,
+								"kind", item.Kind,
+								"name", item.Name,
+								"namespace", namespace,
+								"grantedKind", previous.item.Kind,
+							)
+						}
+						continue
+					}
+					roleBindings[key] = desired
+				}
+
+			case item.Kind == "ClusterRole":
+				// the name of a ClusterRoleBinding identifies its role
+				clusterRoleBindings[desired.name] = desired
+
+			case item.Kind == "Role":
+				s.logger.Warn(
+					"Skipping RoleBinding creation/update due to missing namespace",
+					"ldapGroupBinding", ldapGroupBinding.Name,
+					"roleName", item.Name,
+				)
+
+			default:
+				s.logger.Warn(
+					"Skipping unsupported binding kind",
+					"ldapGroupBinding", ldapGroupBinding.Name,
+					"kind", item.Kind,
+					"name", item.Name,
+				)
+			}
+		}
+	}
+	return clusterRoleBindings, roleBindings, nil
+}
+
+func (s *reconcilerService) getExistingClusterRoleBindings(
+	ctx context.Context,
+	account types.NamespacedName,
+) (map[string]rbacv1.ClusterRoleBinding, error) {
+	clusterRoleBindings, err := s.serviceAccountsSvc.GetClusterRoleBindings(ctx, account)
+	if err != nil {
+		s.logger.Error(
+			"Unable to retrieve current ClusterRoleBindings",
+			"serviceAccount", account,
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to get cluster role bindings: %w", err)
+	}
+
+	result := make(map[string]rbacv1.ClusterRoleBinding, len(clusterRoleBindings))
+	for _, b := range clusterRoleBindings {
+		result[b.Name] = b
+	}
+	return result, nil
+}
+
+func (s *reconcilerService) getExistingRoleBindings(
+	ctx context.Context,
+	account types.NamespacedName,
+) (map[string]rbacv1.RoleBinding, error) {
+	roleBindings, err := s.serviceAccountsSvc.GetRoleBindings(ctx, account)
+	if err != nil {
+		s.logger.Error(
+			"Unable to retrieve current RoleBindings",
+			"serviceAccount", account,
+			"error", err,
+		)
+		return nil, fmt.Errorf("failed to get role bindings: %w", err)
+	}
+
+	// the same binding name may exist in several namespaces
+	result := make(map[string]rbacv1.RoleBinding, len(roleBindings))
+	for _, b := range roleBindings {
+		result[roleBindingKey(b.Namespace, b.Name)] = b
+	}
+	return result, nil
+}
+
+func (s *reconcilerService) ensureClusterRoleBinding(
+	ctx context.Context,
+	account types.NamespacedName,
+	ldapGroupBindingName string,
+	binding v1.LdapGroupBindingItem,
+	bindingName string,
+	existingMap map[string]rbacv1.ClusterRoleBinding,
+) error {
+	existing, exists := existingMap[bindingName]
+
+	if !exists {
+		newBinding, err := s.serviceAccountsSvc.CreateClusterRoleBinding(
+			ctx,
+			account,
+			binding.Name,
+			ldapGroupBindingName,
+		)
+		if err != nil {
+			s.logger.Error(
+				"Failed to create missing ClusterRoleBinding",
+				"serviceAccount", account,
+				"role", binding.Name,
+				"error", err,
+			)
+			return fmt.Errorf("failed to create cluster role binding: %w", err)
+		}
+		s.logger.Info(
+			"Created new ClusterRoleBinding",
+			"serviceAccount", account,
+			"bindingName", newBinding.Name,
+		)
+		s.queueStatus(ldapGroupBindingName)
+	} else {
+		if existing.RoleRef.Name != binding.Name {
+			_, err := s.serviceAccountsSvc.UpdateClusterRoleBinding(
+				ctx,
+				account,
+				binding.Name,
+				ldapGroupBindingName,
+			)
+			if err != nil {
+				s.logger.Error(
+					"Failed to update ClusterRoleBinding to match desired state",
+					"serviceAccount", account,
+					"role", binding.Name,
+					"error", err,
+				)
+				return fmt.Errorf("failed to update cluster role binding: %w", err)
+			}
+			s.logger.Info(
+				"Updated ClusterRoleBinding to match desired state",
+				"serviceAccount", account,
+				"bindingName", existing.Name,
+			)
+		}
+	}
+
+	// delete from existing map to track unused bindings
+	delete(existingMap, bindingName)
+	return nil
+}
+
+func (s *reconcilerService) ensureRoleBinding(
+	ctx context.Context,
+	account types.NamespacedName,
+	ldapGroupBindingName string,
+	binding v1.LdapGroupBindingItem,
+	namespace string,
+	bindingName string,
+	existingMap map[string]rbacv1.RoleBinding,
+) error {
+	key := roleBindingKey(namespace, bindingName)
+	existing, exists := existingMap[key]
+	roleRef := rbacv1.RoleRef{
+		APIGroup: binding.ApiGroup,
+		Kind:     binding.Kind,
+		Name:     binding.Name,
+	}
+
+	if !exists {
+		newBinding, err := s.serviceAccountsSvc.CreateRoleBinding(
+			ctx,
+			account,
+			namespace,
+			ldapGroupBindingName,
+			roleRef,
+		)
+		if err != nil {
+			s.logger.Error(
+				"Failed to create missing RoleBinding",
+				"serviceAccount", account,
+				"role", binding.Name,
+				"namespace", namespace,
+				"error", err,
+			)
+			return fmt.Errorf("failed to create role binding: %w", err)
+		}
+		s.logger.Info(
+			"Created new RoleBinding",
+			"serviceAccount", account,
+			"bindingName", newBinding.Name,
+		)
+		s.queueStatus(ldapGroupBindingName)
+	} else {
+		subjectDiffer := len(existing.Subjects) != 1 ||
+			!serviceaccountssvc.IsSubject(existing.Subjects, account)
+		roleRefDiffer := existing.RoleRef.Name != binding.Name ||
+			existing.RoleRef.Kind != binding.Kind ||
+			existing.RoleRef.APIGroup != binding.ApiGroup
+		if roleRefDiffer {
+			// roleRef is immutable, switching between Role and ClusterRole needs a new binding
+			err := s.serviceAccountsSvc.DeleteRoleBinding(ctx, namespace, existing.Name)
+			if err != nil {
+				return fmt.Errorf("failed to delete role binding: %w", err)
+			}
+			_, err = s.serviceAccountsSvc.CreateRoleBinding(
+				ctx,
+				account,
+				namespace,
+				ldapGroupBindingName,
+				roleRef,
+			)
+			if err != nil {
+				s.logger.Error(
+					"Failed to recreate RoleBinding with new role reference",
+					"serviceAccount", account,
+					"role", binding.Name,
+					"namespace", namespace,
+					"error", err,
+				)
+				return fmt.Errorf("failed to create role binding: %w", err)
+			}
+			s.logger.Info(
+				"Recreated RoleBinding with new role reference",
+				"serviceAccount", account,
+				"bindingName", existing.Name,
+			)
+		} else if subjectDiffer {
+			_, err := s.serviceAccountsSvc.UpdateRoleBinding(
+				ctx,
+				account,
+				namespace,
+				roleRef,
+				ldapGroupBindingName,
+			)
+			if err != nil {
+				s.logger.Error(
+					"Failed to update RoleBinding to match desired state",
+					"serviceAccount", account,
+					"role", binding.Name,
+					"namespace", namespace,
+					"error", err,
+				)
+				return fmt.Errorf("failed to update role binding: %w", err)
+			}
+			s.logger.Info(
+				"Updated RoleBinding to match desired state",
+				"serviceAccount", account,
+				"bindingName", existing.Name,
+			)
+		}
+	}
+
+	// delete from existing map to track unused bindings
+	delete(existingMap, key)
+	return nil
+}
+
+func (s *reconcilerService) removeUnusedClusterRoleBindings(
+	ctx context.Context,
+	account types.NamespacedName,
+	bindings map[string]rbacv1.ClusterRoleBinding,
+) error {
+	for name, binding := range bindings {
+		s.logger.Info(
+			"Removing ClusterRoleBinding not present in desired state",
+			"serviceAccount", account,
+			"name", name,
+			"role", binding.Name,
+		)
+		err := s.serviceAccountsSvc.DeleteClusterRoleBinding(ctx, name)
+		if err != nil {
+			s.logger.Error(
+				"Failed to remove unused ClusterRoleBinding",
+				"serviceAccount", account,
+				"name", name,
+				"error", err,
+			)
+			return fmt.Errorf("failed to delete cluster role binding: %w", err)
+		}
+		s.queueStatus(serviceaccountssvc.BindingSource(binding.ObjectMeta))
+	}
+	return nil
+}
+
+func (s *reconcilerService) removeUnusedRoleBindings(
+	ctx context.Context,
+	account types.NamespacedName,
+	bindings map[string]rbacv1.RoleBinding,
+) error {
+	for _, binding := range bindings {
+		name := binding.Name
+		s.logger.Info(
+			"Removing RoleBinding not present in desired state",
+			"serviceAccount", account,
+			"name", name,
+			"namespace", binding.Namespace,
+			"role", binding.RoleRef.Name,
+		)
+		err := s.serviceAccountsSvc.DeleteRoleBinding(ctx, binding.Namespace, name)
+		if err != nil {
+			s.logger.Error(
+				"Failed to remove unused RoleBinding",
+				"serviceAccount", account,
+				"name", name,
+				"error", err,
+			)
+			return fmt.Errorf("failed to delete role binding: %w", err)
+		}
+		s.queueStatus(serviceaccountssvc.BindingSource(binding.ObjectMeta))
+	}
+	return nil
+}
+
+// roleBindingKey identifies a RoleBinding across namespaces.
+func roleBindingKey(namespace string, name string) string {
+	return namespace + "/" + name
+}
+
+// targetNamespaces returns the existing namespaces a namespaced item is granted in.
+func (s *reconcilerService) targetNamespaces(binding v1.LdapGroupBindingItem) ([]string, error) {
+	var namespaces []string
+	for _, namespace := range binding.ExplicitNamespaces() {
+		exists, err := s.namespacesSvc.Exists(namespace)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			// reported in the LdapGroupBinding status, bound once the namespace is created
+			s.logger.Warn(
+				"Skipping RoleBinding in missing namespace",
+				"roleName", binding.Name,
+				"namespace", namespace,
+			)
+			continue
+		}
+		namespaces = append(namespaces, namespace)
+	}
+
+	if binding.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(binding.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidNamespaceSelector, err)
+		}
+		selected, err := s.namespacesSvc.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, namespace := range selected {
+			if !slices.Contains(namespaces, namespace) {
+				namespaces = append(namespaces, namespace)
+			}
+		}
+	}
+
+	return namespaces, nil
+}
@@ -0,0 +1,174 @@
+package reconcilersvc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	namespacessvc "github.com/froz42/kerbernetes/internal/services/k8s/namespaces"
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// fakeNamespaces serves namespaces from their labels
+type fakeNamespaces map[string]labels.Set
+
+func (f fakeNamespaces) Start(ctx context.Context) error { return nil }
+
+func (f fakeNamespaces) HasSynced() bool { return true }
+
+func (f fakeNamespaces) Exists(name string) (bool, error) {
+	_, ok := f[name]
+	return ok, nil
+}
+
+func (f fakeNamespaces) List(selector labels.Selector) ([]string, error) {
+	var names []string
+	for name, set := range f {
+		if selector.Matches(set) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (f fakeNamespaces) AddEventHandler(handler namespacessvc.EventHandler) {}
+
+func TestCollectBindings(t *testing.T) {
+	view := v1.LdapGroupBindingItem{Kind: "ClusterRole", Name: "view", ApiGroup: rbacv1.GroupName}
+	edit := v1.LdapGroupBindingItem{
+		Kind:      "ClusterRole",
+		Name:      "edit",
+		Namespace: "dev",
+		ApiGroup:  rbacv1.GroupName,
+	}
+	invalidSelector := v1.LdapGroupBindingItem{
+		Kind:     "Role",
+		Name:     "deployer",
+		ApiGroup: rbacv1.GroupName,
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: "Near"},
+			},
+		},
+	}
+	selected := v1.LdapGroupBindingItem{
+		Kind:     "Role",
+		Name:     "deployer",
+		ApiGroup: rbacv1.GroupName,
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"team": "dev"},
+		},
+	}
+	adminRole := v1.LdapGroupBindingItem{
+		Kind:      "Role",
+		Name:      "edit",
+		Namespace: "dev",
+		ApiGroup:  rbacv1.GroupName,
+	}
+
+	tests := []struct {
+		name                    string
+		bindings                map[string][]v1.LdapGroupBindingItem
+		wantClusterRoleBindings []string
+		wantRoleBindings        []string
+	}{
+		{
+			name: "cluster and namespaced items",
+			bindings: map[string][]v1.LdapGroupBindingItem{
+				"developers": {view, edit, selected},
+			},
+			wantClusterRoleBindings: []string{"developers:view"},
+			wantRoleBindings: []string{
+				"dev/developers:deployer",
+				"dev/developers:edit",
+				"staging/developers:deployer",
+			},
+		},
+		{
+			name: "invalid namespace selector skips its item only",
+			bindings: map[string][]v1.LdapGroupBindingItem{
+				"developers": {view, invalidSelector, edit},
+				"operators":  {selected},
+			},
+			wantClusterRoleBindings: []string{"developers:view"},
+			wantRoleBindings: []string{
+				"dev/developers:edit",
+				"dev/operators:deployer",
+				"staging/operators:deployer",
+			},
+		},
+		{
+			name: "duplicate items are collected once",
+			bindings: map[string][]v1.LdapGroupBindingItem{
+				"developers": {view, view, edit, edit},
+			},
+			wantClusterRoleBindings: []string{"developers:view"},
+			wantRoleBindings:        []string{"dev/developers:edit"},
+		},
+		{
+			name: "conflicting role reference keeps the first item",
+			bindings: map[string][]v1.LdapGroupBindingItem{
+				"developers": {edit, adminRole},
+			},
+			wantRoleBindings: []string{"dev/developers:edit"},
+		},
+	}
+
+	s := &reconcilerService{
+		namespacesSvc: fakeNamespaces{
+			"dev":     {"team": "dev"},
+			"staging": {"team": "dev"},
+			"prod":    {"team": "ops"},
+		},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	bindingName := func(roleName string, ldapGroupBindingName string) string {
+		return ldapGroupBindingName + ":" + roleName
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ldapGroupBindings []*v1.LdapGroupBinding
+			for name, items := range tt.bindings {
+				ldapGroupBindings = append(ldapGroupBindings, &v1.LdapGroupBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec:       v1.LdapGroupBindingSpec{Bindings: items},
+				})
+			}
+
+			clusterRoleBindings, roleBindings, err := s.collectBindings(
+				ldapGroupBindings,
+				bindingName,
+			)
+			if err != nil {
+				t.Fatalf("collectBindings: %v", err)
+			}
+			got := sortedKeys(clusterRoleBindings)
+			if !slices.Equal(got, tt.wantClusterRoleBindings) {
+				t.Errorf("ClusterRoleBindings = %v, want %v", got, tt.wantClusterRoleBindings)
+			}
+			if got := sortedKeys(roleBindings); !slices.Equal(got, tt.wantRoleBindings) {
+				t.Errorf("RoleBindings = %v, want %v", got, tt.wantRoleBindings)
+			}
+			if desired, ok := roleBindings["dev/developers:edit"]; ok &&
+				desired.item.Kind != "ClusterRole" {
+				t.Errorf("dev/developers:edit grants a %s", desired.item.Kind)
+			}
+		})
+	}
+}
+
+func sortedKeys(bindings map[string]desiredBinding) []string {
+	var keys []string
+	for key := range bindings {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
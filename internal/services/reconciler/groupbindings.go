@@ -0,0 +1,93 @@
+package reconcilersvc
+
+import (
+	"context"
+	"fmt"
+
+	serviceaccountssvc "github.com/froz42/kerbernetes/internal/services/k8s/serviceaccounts"
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// reconcileGroupBindings grants the roles of an LdapGroupBinding to its group as a Group
+// subject, which is how users authenticated with client certificates, webhook tokens or
+// through the impersonating proxy are bound. Group bindings are removed when none is
+// configured.
+func (s *reconcilerService) reconcileGroupBindings(
+	ctx context.Context,
+	binding *v1.LdapGroupBinding,
+) error {
+	clusterRoleBindings, roleBindings, err := s.serviceAccountsSvc.GetLdapGroupBindingBindings(
+		ctx,
+		binding.Name,
+	)
+	if err != nil {
+		return err
+	}
+
+	desiredClusterRoleBindings := make(map[string]desiredBinding)
+	desiredRoleBindings := make(map[string]desiredBinding)
+	if s.env.UsesGroupSubjects() || s.env.ProxyEnabled {
+		desiredClusterRoleBindings, desiredRoleBindings, err = s.collectBindings(
+			[]*v1.LdapGroupBinding{binding},
+			serviceaccountssvc.GroupBindingName,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	group := binding.Spec.LdapGroupDN
+	for _, desired := range desiredClusterRoleBindings {
+		_, err := s.serviceAccountsSvc.ApplyGroupClusterRoleBinding(
+			ctx,
+			group,
+			desired.item.Name,
+			binding.Name,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to apply group cluster role binding: %w", err)
+		}
+	}
+	for _, desired := range desiredRoleBindings {
+		_, err := s.serviceAccountsSvc.ApplyGroupRoleBinding(
+			ctx,
+			desired.namespace,
+			group,
+			desired.roleRef(),
+			binding.Name,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to apply group role binding: %w", err)
+		}
+	}
+
+	for _, b := range clusterRoleBindings {
+		_, desired := desiredClusterRoleBindings[b.Name]
+		if !serviceaccountssvc.IsGroupBinding(b.Subjects) || desired {
+			continue
+		}
+		err := s.serviceAccountsSvc.DeleteClusterRoleBinding(ctx, b.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete group cluster role binding: %w", err)
+		}
+	}
+	for _, b := range roleBindings {
+		_, desired := desiredRoleBindings[roleBindingKey(b.Namespace, b.Name)]
+		if !serviceaccountssvc.IsGroupBinding(b.Subjects) || desired {
+			continue
+		}
+		err := s.serviceAccountsSvc.DeleteRoleBinding(ctx, b.Namespace, b.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete group role binding: %w", err)
+		}
+	}
+
+	s.logger.Info(
+		"Reconciled group bindings of LdapGroupBinding",
+		"ldapGroupBinding", binding.Name,
+		"clusterRoleBindings", len(desiredClusterRoleBindings),
+		"roleBindings", len(desiredRoleBindings),
+	)
+	return nil
+}
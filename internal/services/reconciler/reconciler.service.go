@@ -0,0 +1,548 @@
+package reconcilersvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/froz42/kerbernetes/internal/security"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	groupssvc "github.com/froz42/kerbernetes/internal/services/groups"
+	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	ldapgroupbindingssvc "github.com/froz42/kerbernetes/internal/services/k8s/ldapgroupbindings"
+	namespacessvc "github.com/froz42/kerbernetes/internal/services/k8s/namespaces"
+	serviceaccountssvc "github.com/froz42/kerbernetes/internal/services/k8s/serviceaccounts"
+	ldapsvc "github.com/froz42/kerbernetes/internal/services/ldap"
+	realmssvc "github.com/froz42/kerbernetes/internal/services/realms"
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	"github.com/samber/do"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// queueKey identifies an object to reconcile, exactly one of its fields is set
+type queueKey struct {
+	serviceAccount         types.NamespacedName
+	ldapGroupBinding       string
+	ldapGroupBindingStatus string
+}
+
+type ReconcilerService interface {
+	// Start processes reconciliation requests and runs the periodic group membership sync
+	Start(ctx context.Context) error
+
+	// ReconcileServiceAccount reconciles the bindings of a service account for the given groups
+	ReconcileServiceAccount(
+		ctx context.Context,
+		account types.NamespacedName,
+		groups []string,
+	) error
+
+	// SyncAll re-resolves the groups of every managed service account and reconciles them
+	SyncAll(ctx context.Context) error
+}
+
+type reconcilerService struct {
+	env                  envsvc.Env
+	k8sSvc               k8ssvc.K8sService
+	serviceAccountsSvc   serviceaccountssvc.ServiceAccountsService
+	ldapGroupBindingsSvc ldapgroupbindingssvc.LdapGroupBindingService
+	namespacesSvc        namespacessvc.NamespacesService
+	ldapSvc              ldapsvc.LDAPSvc
+	groupsSvc            groupssvc.GroupsSvc
+	realmsSvc            realmssvc.RealmsSvc
+	logger               *slog.Logger
+
+	queue workqueue.TypedRateLimitingInterface[queueKey]
+	// locks serializes reconciliations of the same service account, entries only live
+	// while a reconciliation holds or waits for them
+	locksMu sync.Mutex
+	locks   map[types.NamespacedName]*accountLock
+}
+
+// accountLock is the reconciliation lock of a service account
+type accountLock struct {
+	mu sync.Mutex
+	// refs counts the reconciliations holding or waiting for the lock, guarded by locksMu
+	refs int
+}
+
+func NewProvider() func(i *do.Injector) (ReconcilerService, error) {
+	return func(i *do.Injector) (ReconcilerService, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[k8ssvc.K8sService](i),
+			do.MustInvoke[serviceaccountssvc.ServiceAccountsService](i),
+			do.MustInvoke[ldapgroupbindingssvc.LdapGroupBindingService](i),
+			do.MustInvoke[namespacessvc.NamespacesService](i),
+			do.MustInvoke[ldapsvc.LDAPSvc](i),
+			do.MustInvoke[groupssvc.GroupsSvc](i),
+			do.MustInvoke[realmssvc.RealmsSvc](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+func New(
+	configService envsvc.EnvSvc,
+	k8sSvc k8ssvc.K8sService,
+	serviceAccountsSvc serviceaccountssvc.ServiceAccountsService,
+	ldapGroupBindingsSvc ldapgroupbindingssvc.LdapGroupBindingService,
+	namespacesSvc namespacessvc.NamespacesService,
+	ldapSvc ldapsvc.LDAPSvc,
+	groupsSvc groupssvc.GroupsSvc,
+	realmsSvc realmssvc.RealmsSvc,
+	logger *slog.Logger,
+) (ReconcilerService, error) {
+	svc := &reconcilerService{
+		env:                  configService.GetEnv(),
+		k8sSvc:               k8sSvc,
+		serviceAccountsSvc:   serviceAccountsSvc,
+		ldapGroupBindingsSvc: ldapGroupBindingsSvc,
+		namespacesSvc:        namespacesSvc,
+		ldapSvc:              ldapSvc,
+		groupsSvc:            groupsSvc,
+		realmsSvc:            realmsSvc,
+		logger:               logger.With("service", "reconciler"),
+		locks:                map[types.NamespacedName]*accountLock{},
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[queueKey](),
+			workqueue.TypedRateLimitingQueueConfig[queueKey]{Name: "reconciler"},
+		),
+	}
+
+	ldapGroupBindingsSvc.AddEventHandler(svc.onLdapGroupBindingChange)
+	namespacesSvc.AddEventHandler(svc.onNamespaceChange)
+
+	return svc, nil
+}
+
+// Start processes reconciliation requests and runs the periodic group membership sync
+// until the context is cancelled.
+func (s *reconcilerService) Start(ctx context.Context) error {
+	defer s.queue.ShutDown()
+
+	// bindings must be known before reconciling, otherwise everything would be removed
+	if !cache.WaitForCacheSync(
+		ctx.Done(),
+		s.ldapGroupBindingsSvc.HasSynced,
+		s.namespacesSvc.HasSynced,
+	) {
+		return fmt.Errorf("failed to wait for LdapGroupBinding and Namespace cache sync")
+	}
+
+	go s.runWorker(ctx)
+
+	// PAC groups are only known at login, a provider sync would replace them
+	if s.env.PACEnabled && s.groupsSvc.Enabled() && s.env.LDAPSyncInterval > 0 {
+		s.logger.Warn(
+			"Periodic group sync disabled with PAC groups, " +
+				"stale bindings are only revoked when their user logs in again",
+		)
+	}
+	if !s.groupsSvc.Enabled() || s.env.PACEnabled || s.env.LDAPSyncInterval <= 0 {
+		s.logger.Info("Periodic group sync disabled")
+		<-ctx.Done()
+		return nil
+	}
+
+	interval := time.Duration(s.env.LDAPSyncInterval) * time.Second
+	s.logger.Info("Starting periodic group sync", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := s.SyncAll(ctx)
+		if err != nil {
+			s.logger.Error("Periodic group sync failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncAll re-resolves the groups of every managed service account and reconciles them.
+// Service accounts whose user no longer exists in any group provider are stripped of all their bindings.
+func (s *reconcilerService) SyncAll(ctx context.Context) error {
+	serviceAccounts, err := s.serviceAccountsSvc.ListServiceAccounts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list service accounts: %w", err)
+	}
+
+	s.logger.Info("Starting group sync", "serviceAccounts", len(serviceAccounts))
+	failed := 0
+	for _, sa := range serviceAccounts {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		groups, err := s.resolveGroups(serviceaccountssvc.Principal(&sa))
+		if err != nil {
+			// do not touch bindings on transient failures
+			s.logger.Error("Failed to resolve groups", "serviceAccount", sa.Name, "error", err)
+			failed++
+			continue
+		}
+
+		err = s.ReconcileServiceAccount(ctx, serviceaccountssvc.Ref(&sa), groups)
+		if err != nil {
+			s.logger.Error(
+				"Failed to reconcile service account",
+				"serviceAccount",
+				sa.Name,
+				"error",
+				err,
+			)
+			failed++
+		}
+	}
+
+	for _, binding := range s.ldapGroupBindingsSvc.GetBindings() {
+		s.queueStatus(binding.Name)
+	}
+
+	s.logger.Info(
+		"Completed group sync",
+		"serviceAccounts", len(serviceAccounts),
+		"failed", failed,
+	)
+	return nil
+}
+
+// ReconcileServiceAccount reconciles the bindings of a service account for the given groups.
+// The groups are recorded on the service account so that later LdapGroupBinding changes can
+// be applied without resolving them again.
+func (s *reconcilerService) ReconcileServiceAccount(
+	ctx context.Context,
+	account types.NamespacedName,
+	groups []string,
+) error {
+	unlock := s.lock(account)
+	defer unlock()
+
+	err := s.serviceAccountsSvc.SetGroups(ctx, account, groups)
+	if err != nil {
+		return fmt.Errorf("failed to record groups: %w", err)
+	}
+
+	groupsMap := make(map[string]bool)
+	for _, group := range groups {
+		groupsMap[group] = true
+	}
+
+	ldapBindings := s.ldapGroupBindingsSvc.GetBindings()
+	// Filter bindings for the user
+	var userBindings []*v1.LdapGroupBinding
+	for _, binding := range ldapBindings {
+		// bindings being deleted are cleaned up by their finalizer
+		if binding.DeletionTimestamp != nil {
+			continue
+		}
+		if _, exists := groupsMap[binding.Spec.LdapGroupDN]; exists {
+			userBindings = append(userBindings, binding)
+		}
+	}
+
+	return s.reconcileClusterAndRoleBindings(ctx, account, userBindings)
+}
+
+// resolveGroups retrieves the groups of a principal from the group providers.
+// A principal that no longer exists in any provider or whose realm is no longer allowed
+// has no groups.
+func (s *reconcilerService) resolveGroups(principal string) ([]string, error) {
+	_, realm := security.SplitPrincipal(principal)
+	if _, err := s.realmsSvc.Resolve(realm); err != nil {
+		s.logger.Info("Realm of user is no longer allowed", "principal", principal)
+		return nil, nil
+	}
+
+	groups, err := s.groupsSvc.GetGroups(principal)
+	if errors.Is(err, groupssvc.ErrUserNotFound) {
+		s.logger.Info("User no longer exists in the group providers", "principal", principal)
+		return nil, nil
+	}
+	return groups, err
+}
+
+// lock acquires the reconciliation lock of a service account and returns its release function.
+func (s *reconcilerService) lock(account types.NamespacedName) func() {
+	s.locksMu.Lock()
+	l, ok := s.locks[account]
+	if !ok {
+		l = &accountLock{}
+		s.locks[account] = l
+	}
+	l.refs++
+	s.locksMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		s.locksMu.Lock()
+		defer s.locksMu.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(s.locks, account)
+		}
+	}
+}
+
+// onLdapGroupBindingChange queues the reconciliation of a changed LdapGroupBinding.
+func (s *reconcilerService) onLdapGroupBindingChange(oldBinding, newBinding *v1.LdapGroupBinding) {
+	if oldBinding != nil && newBinding != nil &&
+		oldBinding.Generation == newBinding.Generation &&
+		oldBinding.DeletionTimestamp.Equal(newBinding.DeletionTimestamp) &&
+		slices.Equal(oldBinding.Finalizers, newBinding.Finalizers) {
+		// metadata or status only change
+		return
+	}
+
+	name := ""
+	if newBinding != nil {
+		name = newBinding.Name
+	} else {
+		name = oldBinding.Name
+	}
+	s.queue.Add(queueKey{ldapGroupBinding: name})
+}
+
+// onNamespaceChange queues the reconciliation of the LdapGroupBindings targeting a
+// created, deleted or relabelled namespace.
+func (s *reconcilerService) onNamespaceChange(oldNamespace, newNamespace *corev1.Namespace) {
+	if oldNamespace != nil && newNamespace != nil &&
+		maps.Equal(oldNamespace.Labels, newNamespace.Labels) &&
+		oldNamespace.Status.Phase == newNamespace.Status.Phase {
+		return
+	}
+
+	for _, binding := range s.ldapGroupBindingsSvc.GetBindings() {
+		for _, item := range binding.Spec.Bindings {
+			if targetsNamespace(item, oldNamespace) || targetsNamespace(item, newNamespace) {
+				s.queue.Add(queueKey{ldapGroupBinding: binding.Name})
+				break
+			}
+		}
+	}
+}
+
+// targetsNamespace reports whether an item lists or selects a namespace.
+func targetsNamespace(item v1.LdapGroupBindingItem, namespace *corev1.Namespace) bool {
+	if namespace == nil {
+		return false
+	}
+	if slices.Contains(item.ExplicitNamespaces(), namespace.Name) {
+		return true
+	}
+	if item.NamespaceSelector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(item.NamespaceSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(namespace.Labels))
+}
+
+func (s *reconcilerService) runWorker(ctx context.Context) {
+	for s.processNextItem(ctx) {
+	}
+}
+
+func (s *reconcilerService) processNextItem(ctx context.Context) bool {
+	key, shutdown := s.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer s.queue.Done(key)
+
+	var err error
+	switch {
+	case key.ldapGroupBinding != "":
+		err = s.reconcileLdapGroupBinding(ctx, key.ldapGroupBinding)
+	case key.ldapGroupBindingStatus != "":
+		err = s.refreshStatus(ctx, key.ldapGroupBindingStatus)
+	default:
+		err = s.reconcileRecordedGroups(ctx, key.serviceAccount)
+	}
+	if err != nil {
+		s.logger.Error(
+			"Reconciliation failed, requeuing",
+			"serviceAccount", key.serviceAccount,
+			"ldapGroupBinding", key.ldapGroupBinding,
+			"ldapGroupBindingStatus", key.ldapGroupBindingStatus,
+			"error", err,
+		)
+		s.queue.AddRateLimited(key)
+		return true
+	}
+
+	s.queue.Forget(key)
+	return true
+}
+
+// reconcileLdapGroupBinding manages the finalizer of an LdapGroupBinding and queues the
+// reconciliation of every service account it affects.
+func (s *reconcilerService) reconcileLdapGroupBinding(ctx context.Context, name string) error {
+	binding := s.ldapGroupBindingsSvc.GetBinding(name)
+
+	if binding != nil && binding.DeletionTimestamp != nil {
+		return s.finalizeLdapGroupBinding(ctx, name)
+	}
+
+	if binding != nil && !slices.Contains(binding.Finalizers, ldapgroupbindingssvc.Finalizer) {
+		err := s.ldapGroupBindingsSvc.AddFinalizer(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to add finalizer: %w", err)
+		}
+	}
+
+	if binding != nil {
+		err := s.reconcileGroupBindings(ctx, binding)
+		if err != nil {
+			return err
+		}
+	}
+
+	affected := make(map[types.NamespacedName]bool)
+
+	// service accounts holding bindings produced by the previous spec
+	clusterRoleBindings, roleBindings, err := s.serviceAccountsSvc.GetLdapGroupBindingBindings(
+		ctx,
+		name,
+	)
+	if err != nil {
+		return err
+	}
+	for _, b := range clusterRoleBindings {
+		s.collectSubjects(b.Subjects, affected)
+	}
+	for _, b := range roleBindings {
+		s.collectSubjects(b.Subjects, affected)
+	}
+
+	// service accounts member of the group targeted by the current spec
+	if binding != nil {
+		serviceAccounts, err := s.serviceAccountsSvc.ListServiceAccounts(ctx)
+		if err != nil {
+			return err
+		}
+		for _, sa := range serviceAccounts {
+			groups, _ := serviceaccountssvc.RecordedGroups(&sa)
+			if slices.Contains(groups, binding.Spec.LdapGroupDN) {
+				affected[serviceaccountssvc.Ref(&sa)] = true
+			}
+		}
+	}
+
+	s.logger.Info(
+		"Queuing service accounts affected by LdapGroupBinding change",
+		"ldapGroupBinding", name,
+		"serviceAccounts", len(affected),
+	)
+	for account := range affected {
+		s.queue.Add(queueKey{serviceAccount: account})
+	}
+	if binding != nil {
+		s.queueStatus(name)
+	}
+	return nil
+}
+
+// finalizeLdapGroupBinding removes every binding produced by a deleted LdapGroupBinding
+// and releases its finalizer.
+func (s *reconcilerService) finalizeLdapGroupBinding(ctx context.Context, name string) error {
+	clusterRoleBindings, roleBindings, err := s.serviceAccountsSvc.GetLdapGroupBindingBindings(
+		ctx,
+		name,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range clusterRoleBindings {
+		err := s.serviceAccountsSvc.DeleteClusterRoleBinding(ctx, b.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete cluster role binding: %w", err)
+		}
+	}
+	for _, b := range roleBindings {
+		err := s.serviceAccountsSvc.DeleteRoleBinding(ctx, b.Namespace, b.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete role binding: %w", err)
+		}
+	}
+
+	err = s.ldapGroupBindingsSvc.RemoveFinalizer(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to remove finalizer: %w", err)
+	}
+
+	s.logger.Info(
+		"Cleaned up bindings of deleted LdapGroupBinding",
+		"ldapGroupBinding", name,
+		"clusterRoleBindings", len(clusterRoleBindings),
+		"roleBindings", len(roleBindings),
+	)
+	return nil
+}
+
+// reconcileRecordedGroups reconciles a service account with the groups it was last
+// reconciled with, falling back to the group providers when they were never recorded.
+func (s *reconcilerService) reconcileRecordedGroups(
+	ctx context.Context,
+	account types.NamespacedName,
+) error {
+	sa, err := s.serviceAccountsSvc.GetServiceAccount(ctx, account)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	groups, ok := serviceaccountssvc.RecordedGroups(sa)
+	if !ok {
+		if !s.groupsSvc.Enabled() {
+			s.logger.Warn(
+				"Skipping service account without recorded groups",
+				"serviceAccount",
+				account,
+			)
+			return nil
+		}
+		groups, err = s.resolveGroups(serviceaccountssvc.Principal(sa))
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.ReconcileServiceAccount(ctx, account, groups)
+}
+
+// collectSubjects adds the service accounts among the subjects of a managed binding to
+// accounts.
+func (s *reconcilerService) collectSubjects(
+	subjects []rbacv1.Subject,
+	accounts map[types.NamespacedName]bool,
+) {
+	for _, subject := range subjects {
+		if subject.Kind == "ServiceAccount" {
+			accounts[types.NamespacedName{Namespace: subject.Namespace, Name: subject.Name}] = true
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package reconcilersvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// queueStatus queues the status refresh of an LdapGroupBinding.
+func (s *reconcilerService) queueStatus(name string) {
+	if name == "" {
+		return
+	}
+	s.queue.Add(queueKey{ldapGroupBindingStatus: name})
+}
+
+// refreshStatus recomputes the conditions and bound users of an LdapGroupBinding.
+func (s *reconcilerService) refreshStatus(ctx context.Context, name string) error {
+	binding := s.ldapGroupBindingsSvc.GetBinding(name)
+	if binding == nil || binding.DeletionTimestamp != nil {
+		return nil
+	}
+
+	status := *binding.Status.DeepCopy()
+	status.ObservedGeneration = binding.Generation
+	now := metav1.Now()
+	status.LastReconcileTime = &now
+
+	boundUsers, err := s.countBoundUsers(ctx, name)
+	if err != nil {
+		return err
+	}
+	status.BoundUsers = boundUsers
+
+	roleRefCondition, err := s.roleRefCondition(ctx, binding)
+	if err != nil {
+		return err
+	}
+	namespaceCondition, err := s.namespaceCondition(ctx, binding)
+	if err != nil {
+		return err
+	}
+	ldapGroupCondition := s.ldapGroupCondition(binding)
+
+	conditions := []metav1.Condition{roleRefCondition, namespaceCondition, ldapGroupCondition}
+	conditions = append(conditions, readyCondition(conditions))
+	for _, condition := range conditions {
+		condition.ObservedGeneration = binding.Generation
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+
+	err = s.ldapGroupBindingsSvc.UpdateStatus(ctx, name, status)
+	if err != nil {
+		return fmt.Errorf("failed to update status: %w", err)
+	}
+
+	s.logger.Info(
+		"Updated LdapGroupBinding status",
+		"ldapGroupBinding", name,
+		"boundUsers", boundUsers,
+	)
+	return nil
+}
+
+// countBoundUsers counts the service accounts holding bindings produced by an LdapGroupBinding.
+func (s *reconcilerService) countBoundUsers(ctx context.Context, name string) (int32, error) {
+	clusterRoleBindings, roleBindings, err := s.serviceAccountsSvc.GetLdapGroupBindingBindings(
+		ctx,
+		name,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	users := make(map[types.NamespacedName]bool)
+	for _, b := range clusterRoleBindings {
+		s.collectSubjects(b.Subjects, users)
+	}
+	for _, b := range roleBindings {
+		s.collectSubjects(b.Subjects, users)
+	}
+	return int32(len(users)), nil
+}
+
+func (s *reconcilerService) roleRefCondition(
+	ctx context.Context,
+	binding *v1.LdapGroupBinding,
+) (metav1.Condition, error) {
+	var missing []string
+	for _, item := range binding.Spec.Bindings {
+		itemMissing, err := s.missingRoles(ctx, item)
+		if err != nil {
+			return metav1.Condition{}, err
+		}
+		missing = append(missing, itemMissing...)
+	}
+
+	if len(missing) > 0 {
+		return metav1.Condition{
+			Type:    v1.ConditionRoleRefResolved,
+			Status:  metav1.ConditionFalse,
+			Reason:  "RoleNotFound",
+			Message: "Referenced roles not found: " + strings.Join(missing, ", "),
+		}, nil
+	}
+	return metav1.Condition{
+		Type:    v1.ConditionRoleRefResolved,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Resolved",
+		Message: "All referenced roles exist",
+	}, nil
+}
+
+// missingRoles returns the roles referenced by an item that do not exist.
+func (s *reconcilerService) missingRoles(
+	ctx context.Context,
+	item v1.LdapGroupBindingItem,
+) ([]string, error) {
+	rbac := s.k8sSvc.GetClientset().RbacV1()
+
+	switch item.Kind {
+	case "ClusterRole":
+		_, err := rbac.ClusterRoles().Get(ctx, item.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return []string{"ClusterRole/" + item.Name}, nil
+		}
+		return nil, err
+
+	case "Role":
+		if !item.IsNamespaced() {
+			return []string{"Role/" + item.Name}, nil
+		}
+		namespaces, err := s.targetNamespaces(item)
+		if errors.Is(err, errInvalidNamespaceSelector) {
+			// reported by the namespace condition
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var missing []string
+		for _, namespace := range namespaces {
+			_, err := rbac.Roles(namespace).Get(ctx, item.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				missing = append(missing, "Role/"+namespace+"/"+item.Name)
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		return missing, nil
+
+	default:
+		return []string{item.Kind + "/" + item.Name}, nil
+	}
+}
+
+func (s *reconcilerService) namespaceCondition(
+	ctx context.Context,
+	binding *v1.LdapGroupBinding,
+) (metav1.Condition, error) {
+	namespaces := s.k8sSvc.GetClientset().CoreV1().Namespaces()
+
+	var missing []string
+	var invalid []string
+	seen := make(map[string]bool)
+	for _, item := range binding.Spec.Bindings {
+		if item.NamespaceSelector != nil {
+			_, err := metav1.LabelSelectorAsSelector(item.NamespaceSelector)
+			if err != nil {
+				invalid = append(invalid, item.Kind+"/"+item.Name+": "+err.Error())
+			}
+		}
+
+		// selected namespaces exist by definition
+		for _, namespace := range item.ExplicitNamespaces() {
+			if seen[namespace] {
+				continue
+			}
+			seen[namespace] = true
+
+			_, err := namespaces.Get(ctx, namespace, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				missing = append(missing, namespace)
+			} else if err != nil {
+				return metav1.Condition{}, err
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		return metav1.Condition{
+			Type:   v1.ConditionNamespaceExists,
+			Status: metav1.ConditionFalse,
+			Reason: "InvalidNamespaceSelector",
+			Message: "Items skipped for an invalid namespace selector: " + strings.Join(
+				invalid,
+				"; ",
+			),
+		}, nil
+	}
+	if len(missing) > 0 {
+		return metav1.Condition{
+			Type:    v1.ConditionNamespaceExists,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NamespaceNotFound",
+			Message: "Namespaces not found: " + strings.Join(missing, ", "),
+		}, nil
+	}
+	return metav1.Condition{
+		Type:    v1.ConditionNamespaceExists,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Exists",
+		Message: "All referenced namespaces exist",
+	}, nil
+}
+
+func (s *reconcilerService) ldapGroupCondition(binding *v1.LdapGroupBinding) metav1.Condition {
+	if !s.env.LDAPEnabled {
+		return metav1.Condition{
+			Type:    v1.ConditionLdapGroupResolved,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "LdapDisabled",
+			Message: "LDAP integration is disabled",
+		}
+	}
+
+	exists, err := s.ldapSvc.GroupExists(binding.Spec.LdapGroupDN)
+	if err != nil {
+		s.logger.Warn(
+			"Failed to look up LDAP group",
+			"ldapGroupBinding", binding.Name,
+			"dn", binding.Spec.LdapGroupDN,
+			"error", err,
+		)
+		return metav1.Condition{
+			Type:    v1.ConditionLdapGroupResolved,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "LdapError",
+			Message: "Failed to look up the LDAP group: " + err.Error(),
+		}
+	}
+	if !exists {
+		return metav1.Condition{
+			Type:    v1.ConditionLdapGroupResolved,
+			Status:  metav1.ConditionFalse,
+			Reason:  "GroupNotFound",
+			Message: "LDAP group not found in the directory",
+		}
+	}
+	return metav1.Condition{
+		Type:    v1.ConditionLdapGroupResolved,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Found",
+		Message: "LDAP group exists in the directory",
+	}
+}
+
+// readyCondition is true when no other condition is false.
+func readyCondition(conditions []metav1.Condition) metav1.Condition {
+	var failed []string
+	for _, condition := range conditions {
+		if condition.Status == metav1.ConditionFalse {
+			failed = append(failed, condition.Type)
+		}
+	}
+
+	if len(failed) > 0 {
+		return metav1.Condition{
+			Type:    v1.ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NotResolved",
+			Message: "Unsatisfied conditions: " + strings.Join(failed, ", "),
+		}
+	}
+	return metav1.Condition{
+		Type:    v1.ConditionReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Resolved",
+		Message: "LdapGroupBinding is ready",
+	}
+}
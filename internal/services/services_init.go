@@ -2,11 +2,23 @@ package services
 
 import (
 	authsvc "github.com/froz42/kerbernetes/internal/services/auth"
+	clusterssvc "github.com/froz42/kerbernetes/internal/services/clusters"
 	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	groupssvc "github.com/froz42/kerbernetes/internal/services/groups"
 	k8ssvc "github.com/froz42/kerbernetes/internal/services/k8s"
+	certificatessvc "github.com/froz42/kerbernetes/internal/services/k8s/certificates"
 	ldapgroupbindingssvc "github.com/froz42/kerbernetes/internal/services/k8s/ldapgroupbindings"
+	namespacessvc "github.com/froz42/kerbernetes/internal/services/k8s/namespaces"
 	serviceaccountssvc "github.com/froz42/kerbernetes/internal/services/k8s/serviceaccounts"
+	signingkeyssvc "github.com/froz42/kerbernetes/internal/services/k8s/signingkeys"
+	keytabsvc "github.com/froz42/kerbernetes/internal/services/keytab"
+	kubeconfigsvc "github.com/froz42/kerbernetes/internal/services/kubeconfig"
 	ldapsvc "github.com/froz42/kerbernetes/internal/services/ldap"
+	pacsvc "github.com/froz42/kerbernetes/internal/services/pac"
+	proxysvc "github.com/froz42/kerbernetes/internal/services/proxy"
+	realmssvc "github.com/froz42/kerbernetes/internal/services/realms"
+	reconcilersvc "github.com/froz42/kerbernetes/internal/services/reconciler"
+	tokenssvc "github.com/froz42/kerbernetes/internal/services/tokens"
 	"github.com/samber/do"
 )
 
@@ -15,7 +27,19 @@ func InitServices(i *do.Injector) error {
 	do.Provide(i, authsvc.NewProvider())
 	do.Provide(i, k8ssvc.NewProvider())
 	do.Provide(i, ldapsvc.NewProvider())
+	do.Provide(i, groupssvc.NewProvider())
 	do.Provide(i, ldapgroupbindingssvc.NewProvider())
+	do.Provide(i, namespacessvc.NewProvider())
 	do.Provide(i, serviceaccountssvc.NewProvider())
+	do.Provide(i, certificatessvc.NewProvider())
+	do.Provide(i, signingkeyssvc.NewProvider())
+	do.Provide(i, tokenssvc.NewProvider())
+	do.Provide(i, reconcilersvc.NewProvider())
+	do.Provide(i, pacsvc.NewProvider())
+	do.Provide(i, realmssvc.NewProvider())
+	do.Provide(i, keytabsvc.NewProvider())
+	do.Provide(i, kubeconfigsvc.NewProvider())
+	do.Provide(i, proxysvc.NewProvider())
+	do.Provide(i, clusterssvc.NewProvider())
 	return nil
 }
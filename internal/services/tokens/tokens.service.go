@@ -0,0 +1,212 @@
+package tokenssvc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/froz42/kerbernetes/internal/security"
+	envsvc "github.com/froz42/kerbernetes/internal/services/env"
+	signingkeyssvc "github.com/froz42/kerbernetes/internal/services/k8s/signingkeys"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/samber/do"
+)
+
+// Issuer is the iss claim of the webhook tokens, ID tokens use the OIDC issuer URL
+const Issuer = "kerbernetes"
+
+// leeway is the clock skew tolerated between replicas
+const leeway = 30 * time.Second
+
+var (
+	// ErrReservedIdentity is returned when the principal would impersonate a system identity
+	ErrReservedIdentity = errors.New("principal maps to a reserved Kubernetes identity")
+	// ErrInvalidToken is returned for tokens that are malformed, expired or not signed by a
+	// retained signing key
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrAudienceMismatch is returned when the token is not valid for any requested audience
+	ErrAudienceMismatch = errors.New("token audiences do not match")
+)
+
+// Identity is the user a token authenticates
+type Identity struct {
+	Username string
+	Groups   []string
+	// Audiences are the requested audiences the token is valid for
+	Audiences []string
+}
+
+type claims struct {
+	jwt.Claims
+	PreferredUsername string   `json:"preferred_username,omitempty"`
+	Groups            []string `json:"groups,omitempty"`
+}
+
+type TokensSvc interface {
+	// Issue mints a token carrying a principal and its groups, valid for the token duration
+	Issue(principal string, groups []string) (token string, expiresAt time.Time, err error)
+
+	// IssueIDToken mints an ID token of the OIDC issuer carrying a principal and its groups,
+	// valid for the token duration
+	IssueIDToken(principal string, groups []string) (token string, expiresAt time.Time, err error)
+
+	// Review verifies a token and returns its identity. The token must be valid for one of
+	// the audiences, or for the configured audience when none is given.
+	Review(ctx context.Context, token string, audiences []string) (*Identity, error)
+}
+
+type tokensSvc struct {
+	env            envsvc.Env
+	signingKeysSvc signingkeyssvc.SigningKeysSvc
+	logger         *slog.Logger
+}
+
+func NewProvider() func(i *do.Injector) (TokensSvc, error) {
+	return func(i *do.Injector) (TokensSvc, error) {
+		return New(
+			do.MustInvoke[envsvc.EnvSvc](i),
+			do.MustInvoke[signingkeyssvc.SigningKeysSvc](i),
+			do.MustInvoke[*slog.Logger](i),
+		)
+	}
+}
+
+func New(
+	envSvc envsvc.EnvSvc,
+	signingKeysSvc signingkeyssvc.SigningKeysSvc,
+	logger *slog.Logger,
+) (TokensSvc, error) {
+	return &tokensSvc{
+		env:            envSvc.GetEnv(),
+		signingKeysSvc: signingKeysSvc,
+		logger:         logger.With("service", "tokens"),
+	}, nil
+}
+
+func (svc *tokensSvc) Issue(principal string, groups []string) (string, time.Time, error) {
+	return svc.sign(principal, groups, func(c *claims) {
+		c.Issuer = Issuer
+		c.Audience = jwt.Audience{svc.env.TokenAudience}
+	})
+}
+
+func (svc *tokensSvc) IssueIDToken(principal string, groups []string) (string, time.Time, error) {
+	return svc.sign(principal, groups, func(c *claims) {
+		username, _ := security.SplitPrincipal(principal)
+		c.Issuer = svc.env.OIDCIssuerURL()
+		c.Audience = jwt.Audience{svc.env.OIDCClientID}
+		c.PreferredUsername = username
+	})
+}
+
+// sign mints a token with the principal as subject and its groups, the issuer specific
+// claims are set by withClaims.
+func (svc *tokensSvc) sign(
+	principal string,
+	groups []string,
+	withClaims func(c *claims),
+) (string, time.Time, error) {
+	if security.IsReservedIdentity(principal) {
+		return "", time.Time{}, ErrReservedIdentity
+	}
+
+	// a group such as system:masters would grant more than any LdapGroupBinding
+	allowed := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if security.IsReservedIdentity(group) {
+			svc.logger.Warn("Dropping reserved group", "principal", principal, "group", group)
+			continue
+		}
+		allowed = append(allowed, group)
+	}
+
+	signer, err := svc.signingKeysSvc.Signer()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	id, err := tokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(svc.env.TokenDuration) * time.Second)
+	c := claims{
+		Claims: jwt.Claims{
+			Subject:   principal,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Expiry:    jwt.NewNumericDate(expiresAt),
+			ID:        id,
+		},
+		Groups: allowed,
+	}
+	withClaims(&c)
+	token, err := jwt.Signed(signer).Claims(c).Serialize()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+func (svc *tokensSvc) Review(
+	ctx context.Context,
+	token string,
+	audiences []string,
+) (*Identity, error) {
+	parsed, err := jwt.ParseSigned(token, []jose.SignatureAlgorithm{signingkeyssvc.Algorithm})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if len(parsed.Headers) != 1 {
+		return nil, fmt.Errorf("%w: expected a single signature", ErrInvalidToken)
+	}
+
+	key, err := svc.signingKeysSvc.PublicKey(ctx, parsed.Headers[0].KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	var c claims
+	if err := parsed.Claims(key.Key, &c); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	err = c.ValidateWithLeeway(jwt.Expected{Issuer: Issuer, Time: time.Now()}, leeway)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if c.Subject == "" || security.IsReservedIdentity(c.Subject) {
+		return nil, fmt.Errorf("%w: invalid subject", ErrInvalidToken)
+	}
+
+	identity := &Identity{Username: c.Subject, Groups: c.Groups}
+	if len(audiences) == 0 {
+		// tokens of other clusters sharing the signing keys carry their own audience
+		if !slices.Contains(c.Audience, svc.env.TokenAudience) {
+			return nil, ErrAudienceMismatch
+		}
+		return identity, nil
+	}
+	for _, audience := range audiences {
+		if slices.Contains(c.Audience, audience) {
+			identity.Audiences = append(identity.Audiences, audience)
+		}
+	}
+	if len(identity.Audiences) == 0 {
+		return nil, ErrAudienceMismatch
+	}
+	return identity, nil
+}
+
+func tokenID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
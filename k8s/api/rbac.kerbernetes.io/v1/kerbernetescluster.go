@@ -0,0 +1,74 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.spec.kubeconfigSecretRef.name`,description="Secret holding the kubeconfig of the cluster"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,description="Whether credentials can be issued for the cluster"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// KerbernetesCluster registers a cluster credentials are issued for, in addition to the
+// cluster kerbernetes runs in
+type KerbernetesCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              KerbernetesClusterSpec `json:"spec"`
+	// +optional
+	Status KerbernetesClusterStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KerbernetesClusterList contains a list of KerbernetesCluster
+type KerbernetesClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []KerbernetesCluster `json:"items"`
+}
+
+type KerbernetesClusterSpec struct {
+	// kubeconfigSecretRef references the Secret of the kerbernetes namespace holding the
+	// kubeconfig kerbernetes uses to manage the cluster.
+	KubeconfigSecretRef SecretKeyReference `json:"kubeconfigSecretRef"`
+	// namespace is the namespace of the service accounts in the cluster, the kerbernetes
+	// namespace name when empty. Realms with their own namespace keep it.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// server is the API server URL of the kubeconfigs generated for the cluster, the one of
+	// the kubeconfig Secret when empty.
+	// +optional
+	Server string `json:"server,omitempty"`
+	// tokenAudience is the audience of the tokens issued for the cluster: of the service
+	// account tokens, the configured one when empty, or of the webhook tokens and the client
+	// ID of the ID tokens, kerbernetes:<name> when empty.
+	// +optional
+	TokenAudience string `json:"tokenAudience,omitempty"`
+}
+
+type SecretKeyReference struct {
+	// name is the name of the Secret.
+	Name string `json:"name"`
+	// key is the key of the Secret holding the value.
+	// +optional
+	// +kubebuilder:default=kubeconfig
+	Key string `json:"key,omitempty"`
+}
+
+type KerbernetesClusterStatus struct {
+	// observedGeneration is the most recent generation loaded.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// conditions describe the current state of the KerbernetesCluster, Ready is true once
+	// the kubeconfig is loaded and the caches of the cluster are synced.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
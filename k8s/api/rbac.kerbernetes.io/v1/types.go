@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"slices"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -9,15 +11,21 @@ import (
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="LDAP Group",type=string,JSONPath=`.spec.ldapGroupDN`,description="The LDAP group distinguished name"
 // +kubebuilder:printcolumn:name="Binding Kind",type=string,JSONPath=`.spec.bindings[0].kind`,description="Kind of the first binding"
 // +kubebuilder:printcolumn:name="Binding Name",type=string,JSONPath=`.spec.bindings[0].name`,description="Name of the first binding"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,description="Whether the binding is fully resolved"
+// +kubebuilder:printcolumn:name="Users",type=integer,JSONPath=`.status.boundUsers`,description="Number of users bound"
+// +kubebuilder:printcolumn:name="Last Reconcile",type=date,JSONPath=`.status.lastReconcileTime`,description="Time of the last reconcile"
 
 // LdapGroupBinding is the Schema for ldapgroupbinding API
 type LdapGroupBinding struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 	Spec              LdapGroupBindingSpec `json:"spec"`
+	// +optional
+	Status LdapGroupBindingStatus `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -34,6 +42,7 @@ type LdapGroupBindingSpec struct {
 	Bindings    []LdapGroupBindingItem `json:"bindings"`
 }
 
+// +kubebuilder:validation:XValidation:rule="self.kind != 'Role' || (has(self.namespace) && size(self.namespace) > 0) || (has(self.namespaces) && size(self.namespaces) > 0) || has(self.namespaceSelector)",message="namespace, namespaces or namespaceSelector is required if the kind is Role"
 type LdapGroupBindingItem struct {
 	// kind is the kind of the resource to bind to the LDAP group.
 	// +kubebuilder:validation:Enum=ClusterRole;Role
@@ -41,9 +50,66 @@ type LdapGroupBindingItem struct {
 	// name is the name of the resource to bind to the LDAP group.
 	Name string `json:"name"`
 	// namespace is the namespace of the resource to bind to the LDAP group.
-	// This field is required if the kind is Role.
+	// One of namespace, namespaces or namespaceSelector is required if the kind is Role.
+	// When any of them is set on a ClusterRole, the ClusterRole is granted in those
+	// namespaces only through RoleBindings.
 	// +optional
 	Namespace string `json:"namespace,omitempty"`
+	// namespaces lists additional namespaces to bind the resource in.
+	// +optional
+	// +listType=set
+	Namespaces []string `json:"namespaces,omitempty"`
+	// namespaceSelector binds the resource in every namespace matching the selector.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 	// apiGroup is the API group of the resource to bind to the LDAP group.
 	ApiGroup string `json:"apiGroup"`
 }
+
+// Condition types of an LdapGroupBinding.
+const (
+	// ConditionReady is true when every other condition is satisfied.
+	ConditionReady = "Ready"
+	// ConditionRoleRefResolved is true when every referenced Role and ClusterRole exists.
+	ConditionRoleRefResolved = "RoleRefResolved"
+	// ConditionNamespaceExists is true when every referenced namespace exists.
+	ConditionNamespaceExists = "NamespaceExists"
+	// ConditionLdapGroupResolved is true when the LDAP group exists in the directory.
+	ConditionLdapGroupResolved = "LdapGroupResolved"
+)
+
+type LdapGroupBindingStatus struct {
+	// observedGeneration is the most recent generation reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// boundUsers is the number of users currently bound through this LdapGroupBinding.
+	// +optional
+	BoundUsers int32 `json:"boundUsers"`
+	// lastReconcileTime is the time of the last reconcile.
+	// +optional
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+	// conditions describe the current state of the LdapGroupBinding.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// IsNamespaced reports whether the item is granted through RoleBindings.
+func (item *LdapGroupBindingItem) IsNamespaced() bool {
+	return item.Namespace != "" || len(item.Namespaces) > 0 || item.NamespaceSelector != nil
+}
+
+// ExplicitNamespaces returns the namespaces the item lists by name.
+func (item *LdapGroupBindingItem) ExplicitNamespaces() []string {
+	var namespaces []string
+	if item.Namespace != "" {
+		namespaces = append(namespaces, item.Namespace)
+	}
+	for _, namespace := range item.Namespaces {
+		if !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
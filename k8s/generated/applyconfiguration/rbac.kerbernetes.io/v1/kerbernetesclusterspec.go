@@ -0,0 +1,50 @@
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1
+
+// KerbernetesClusterSpecApplyConfiguration represents a declarative configuration of the KerbernetesClusterSpec type for use
+// with apply.
+type KerbernetesClusterSpecApplyConfiguration struct {
+	KubeconfigSecretRef *SecretKeyReferenceApplyConfiguration `json:"kubeconfigSecretRef,omitempty"`
+	Namespace           *string                               `json:"namespace,omitempty"`
+	Server              *string                               `json:"server,omitempty"`
+	TokenAudience       *string                               `json:"tokenAudience,omitempty"`
+}
+
+// KerbernetesClusterSpecApplyConfiguration constructs a declarative configuration of the KerbernetesClusterSpec type for use with
+// apply.
+func KerbernetesClusterSpec() *KerbernetesClusterSpecApplyConfiguration {
+	return &KerbernetesClusterSpecApplyConfiguration{}
+}
+
+// WithKubeconfigSecretRef sets the KubeconfigSecretRef field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the KubeconfigSecretRef field is set to the value of the last call.
+func (b *KerbernetesClusterSpecApplyConfiguration) WithKubeconfigSecretRef(value *SecretKeyReferenceApplyConfiguration) *KerbernetesClusterSpecApplyConfiguration {
+	b.KubeconfigSecretRef = value
+	return b
+}
+
+// WithNamespace sets the Namespace field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Namespace field is set to the value of the last call.
+func (b *KerbernetesClusterSpecApplyConfiguration) WithNamespace(value string) *KerbernetesClusterSpecApplyConfiguration {
+	b.Namespace = &value
+	return b
+}
+
+// WithServer sets the Server field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Server field is set to the value of the last call.
+func (b *KerbernetesClusterSpecApplyConfiguration) WithServer(value string) *KerbernetesClusterSpecApplyConfiguration {
+	b.Server = &value
+	return b
+}
+
+// WithTokenAudience sets the TokenAudience field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the TokenAudience field is set to the value of the last call.
+func (b *KerbernetesClusterSpecApplyConfiguration) WithTokenAudience(value string) *KerbernetesClusterSpecApplyConfiguration {
+	b.TokenAudience = &value
+	return b
+}
@@ -0,0 +1,41 @@
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	metav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// KerbernetesClusterStatusApplyConfiguration represents a declarative configuration of the KerbernetesClusterStatus type for use
+// with apply.
+type KerbernetesClusterStatusApplyConfiguration struct {
+	ObservedGeneration *int64                               `json:"observedGeneration,omitempty"`
+	Conditions         []metav1.ConditionApplyConfiguration `json:"conditions,omitempty"`
+}
+
+// KerbernetesClusterStatusApplyConfiguration constructs a declarative configuration of the KerbernetesClusterStatus type for use with
+// apply.
+func KerbernetesClusterStatus() *KerbernetesClusterStatusApplyConfiguration {
+	return &KerbernetesClusterStatusApplyConfiguration{}
+}
+
+// WithObservedGeneration sets the ObservedGeneration field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ObservedGeneration field is set to the value of the last call.
+func (b *KerbernetesClusterStatusApplyConfiguration) WithObservedGeneration(value int64) *KerbernetesClusterStatusApplyConfiguration {
+	b.ObservedGeneration = &value
+	return b
+}
+
+// WithConditions adds the given value to the Conditions field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Conditions field.
+func (b *KerbernetesClusterStatusApplyConfiguration) WithConditions(values ...*metav1.ConditionApplyConfiguration) *KerbernetesClusterStatusApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithConditions")
+		}
+		b.Conditions = append(b.Conditions, *values[i])
+	}
+	return b
+}
@@ -13,7 +13,8 @@ import (
 type LdapGroupBindingApplyConfiguration struct {
 	metav1.TypeMetaApplyConfiguration    `json:",inline"`
 	*metav1.ObjectMetaApplyConfiguration `json:"metadata,omitempty"`
-	Spec                                 *LdapGroupBindingSpecApplyConfiguration `json:"spec,omitempty"`
+	Spec                                 *LdapGroupBindingSpecApplyConfiguration   `json:"spec,omitempty"`
+	Status                               *LdapGroupBindingStatusApplyConfiguration `json:"status,omitempty"`
 }
 
 // LdapGroupBinding constructs a declarative configuration of the LdapGroupBinding type for use with
@@ -193,6 +194,14 @@ func (b *LdapGroupBindingApplyConfiguration) WithSpec(value *LdapGroupBindingSpe
 	return b
 }
 
+// WithStatus sets the Status field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Status field is set to the value of the last call.
+func (b *LdapGroupBindingApplyConfiguration) WithStatus(value *LdapGroupBindingStatusApplyConfiguration) *LdapGroupBindingApplyConfiguration {
+	b.Status = value
+	return b
+}
+
 // GetKind retrieves the value of the Kind field in the declarative configuration.
 func (b *LdapGroupBindingApplyConfiguration) GetKind() *string {
 	return b.TypeMetaApplyConfiguration.Kind
@@ -2,13 +2,19 @@
 
 package v1
 
+import (
+	metav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
 // LdapGroupBindingItemApplyConfiguration represents a declarative configuration of the LdapGroupBindingItem type for use
 // with apply.
 type LdapGroupBindingItemApplyConfiguration struct {
-	Kind      *string `json:"kind,omitempty"`
-	Name      *string `json:"name,omitempty"`
-	Namespace *string `json:"namespace,omitempty"`
-	ApiGroup  *string `json:"apiGroup,omitempty"`
+	Kind              *string                                 `json:"kind,omitempty"`
+	Name              *string                                 `json:"name,omitempty"`
+	Namespace         *string                                 `json:"namespace,omitempty"`
+	Namespaces        []string                                `json:"namespaces,omitempty"`
+	NamespaceSelector *metav1.LabelSelectorApplyConfiguration `json:"namespaceSelector,omitempty"`
+	ApiGroup          *string                                 `json:"apiGroup,omitempty"`
 }
 
 // LdapGroupBindingItemApplyConfiguration constructs a declarative configuration of the LdapGroupBindingItem type for use with
@@ -41,6 +47,24 @@ func (b *LdapGroupBindingItemApplyConfiguration) WithNamespace(value string) *Ld
 	return b
 }
 
+// WithNamespaces adds the given value to the Namespaces field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Namespaces field.
+func (b *LdapGroupBindingItemApplyConfiguration) WithNamespaces(values ...string) *LdapGroupBindingItemApplyConfiguration {
+	for i := range values {
+		b.Namespaces = append(b.Namespaces, values[i])
+	}
+	return b
+}
+
+// WithNamespaceSelector sets the NamespaceSelector field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the NamespaceSelector field is set to the value of the last call.
+func (b *LdapGroupBindingItemApplyConfiguration) WithNamespaceSelector(value *metav1.LabelSelectorApplyConfiguration) *LdapGroupBindingItemApplyConfiguration {
+	b.NamespaceSelector = value
+	return b
+}
+
 // WithApiGroup sets the ApiGroup field in the declarative configuration to the given value
 // and returns the receiver, so that objects can be built by chaining "With" function invocations.
 // If called multiple times, the ApiGroup field is set to the value of the last call.
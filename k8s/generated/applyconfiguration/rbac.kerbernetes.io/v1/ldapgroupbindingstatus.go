@@ -0,0 +1,60 @@
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	applyconfigurationsmetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// LdapGroupBindingStatusApplyConfiguration represents a declarative configuration of the LdapGroupBindingStatus type for use
+// with apply.
+type LdapGroupBindingStatusApplyConfiguration struct {
+	ObservedGeneration *int64                                                  `json:"observedGeneration,omitempty"`
+	BoundUsers         *int32                                                  `json:"boundUsers,omitempty"`
+	LastReconcileTime  *metav1.Time                                            `json:"lastReconcileTime,omitempty"`
+	Conditions         []applyconfigurationsmetav1.ConditionApplyConfiguration `json:"conditions,omitempty"`
+}
+
+// LdapGroupBindingStatusApplyConfiguration constructs a declarative configuration of the LdapGroupBindingStatus type for use with
+// apply.
+func LdapGroupBindingStatus() *LdapGroupBindingStatusApplyConfiguration {
+	return &LdapGroupBindingStatusApplyConfiguration{}
+}
+
+// WithObservedGeneration sets the ObservedGeneration field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ObservedGeneration field is set to the value of the last call.
+func (b *LdapGroupBindingStatusApplyConfiguration) WithObservedGeneration(value int64) *LdapGroupBindingStatusApplyConfiguration {
+	b.ObservedGeneration = &value
+	return b
+}
+
+// WithBoundUsers sets the BoundUsers field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the BoundUsers field is set to the value of the last call.
+func (b *LdapGroupBindingStatusApplyConfiguration) WithBoundUsers(value int32) *LdapGroupBindingStatusApplyConfiguration {
+	b.BoundUsers = &value
+	return b
+}
+
+// WithLastReconcileTime sets the LastReconcileTime field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the LastReconcileTime field is set to the value of the last call.
+func (b *LdapGroupBindingStatusApplyConfiguration) WithLastReconcileTime(value metav1.Time) *LdapGroupBindingStatusApplyConfiguration {
+	b.LastReconcileTime = &value
+	return b
+}
+
+// WithConditions adds the given value to the Conditions field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Conditions field.
+func (b *LdapGroupBindingStatusApplyConfiguration) WithConditions(values ...*applyconfigurationsmetav1.ConditionApplyConfiguration) *LdapGroupBindingStatusApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithConditions")
+		}
+		b.Conditions = append(b.Conditions, *values[i])
+	}
+	return b
+}
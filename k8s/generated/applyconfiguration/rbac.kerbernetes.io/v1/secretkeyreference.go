@@ -0,0 +1,32 @@
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1
+
+// SecretKeyReferenceApplyConfiguration represents a declarative configuration of the SecretKeyReference type for use
+// with apply.
+type SecretKeyReferenceApplyConfiguration struct {
+	Name *string `json:"name,omitempty"`
+	Key  *string `json:"key,omitempty"`
+}
+
+// SecretKeyReferenceApplyConfiguration constructs a declarative configuration of the SecretKeyReference type for use with
+// apply.
+func SecretKeyReference() *SecretKeyReferenceApplyConfiguration {
+	return &SecretKeyReferenceApplyConfiguration{}
+}
+
+// WithName sets the Name field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Name field is set to the value of the last call.
+func (b *SecretKeyReferenceApplyConfiguration) WithName(value string) *SecretKeyReferenceApplyConfiguration {
+	b.Name = &value
+	return b
+}
+
+// WithKey sets the Key field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Key field is set to the value of the last call.
+func (b *SecretKeyReferenceApplyConfiguration) WithKey(value string) *SecretKeyReferenceApplyConfiguration {
+	b.Key = &value
+	return b
+}
@@ -16,12 +16,22 @@ import (
 func ForKind(kind schema.GroupVersionKind) interface{} {
 	switch kind {
 	// Group=rbac.kerbernetes.io, Version=v1
+	case v1.SchemeGroupVersion.WithKind("KerbernetesCluster"):
+		return &rbackerbernetesiov1.KerbernetesClusterApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("KerbernetesClusterSpec"):
+		return &rbackerbernetesiov1.KerbernetesClusterSpecApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("KerbernetesClusterStatus"):
+		return &rbackerbernetesiov1.KerbernetesClusterStatusApplyConfiguration{}
 	case v1.SchemeGroupVersion.WithKind("LdapGroupBinding"):
 		return &rbackerbernetesiov1.LdapGroupBindingApplyConfiguration{}
 	case v1.SchemeGroupVersion.WithKind("LdapGroupBindingItem"):
 		return &rbackerbernetesiov1.LdapGroupBindingItemApplyConfiguration{}
 	case v1.SchemeGroupVersion.WithKind("LdapGroupBindingSpec"):
 		return &rbackerbernetesiov1.LdapGroupBindingSpecApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("LdapGroupBindingStatus"):
+		return &rbackerbernetesiov1.LdapGroupBindingStatusApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("SecretKeyReference"):
+		return &rbackerbernetesiov1.SecretKeyReferenceApplyConfiguration{}
 
 	}
 	return nil
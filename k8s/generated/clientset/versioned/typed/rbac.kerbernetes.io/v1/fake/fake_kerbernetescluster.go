@@ -0,0 +1,37 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	rbackerbernetesiov1 "github.com/froz42/kerbernetes/k8s/generated/applyconfiguration/rbac.kerbernetes.io/v1"
+	typedrbackerbernetesiov1 "github.com/froz42/kerbernetes/k8s/generated/clientset/versioned/typed/rbac.kerbernetes.io/v1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeKerbernetesClusters implements KerbernetesClusterInterface
+type fakeKerbernetesClusters struct {
+	*gentype.FakeClientWithListAndApply[*v1.KerbernetesCluster, *v1.KerbernetesClusterList, *rbackerbernetesiov1.KerbernetesClusterApplyConfiguration]
+	Fake *FakeRbacKerbernetesV1
+}
+
+func newFakeKerbernetesClusters(fake *FakeRbacKerbernetesV1) typedrbackerbernetesiov1.KerbernetesClusterInterface {
+	return &fakeKerbernetesClusters{
+		gentype.NewFakeClientWithListAndApply[*v1.KerbernetesCluster, *v1.KerbernetesClusterList, *rbackerbernetesiov1.KerbernetesClusterApplyConfiguration](
+			fake.Fake,
+			"",
+			v1.SchemeGroupVersion.WithResource("kerbernetesclusters"),
+			v1.SchemeGroupVersion.WithKind("KerbernetesCluster"),
+			func() *v1.KerbernetesCluster { return &v1.KerbernetesCluster{} },
+			func() *v1.KerbernetesClusterList { return &v1.KerbernetesClusterList{} },
+			func(dst, src *v1.KerbernetesClusterList) { dst.ListMeta = src.ListMeta },
+			func(list *v1.KerbernetesClusterList) []*v1.KerbernetesCluster {
+				return gentype.ToPointerSlice(list.Items)
+			},
+			func(list *v1.KerbernetesClusterList, items []*v1.KerbernetesCluster) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -12,6 +12,10 @@ type FakeRbacKerbernetesV1 struct {
 	*testing.Fake
 }
 
+func (c *FakeRbacKerbernetesV1) KerbernetesClusters() v1.KerbernetesClusterInterface {
+	return newFakeKerbernetesClusters(c)
+}
+
 func (c *FakeRbacKerbernetesV1) LdapGroupBindings() v1.LdapGroupBindingInterface {
 	return newFakeLdapGroupBindings(c)
 }
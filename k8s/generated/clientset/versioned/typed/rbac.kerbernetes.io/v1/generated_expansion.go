@@ -2,4 +2,6 @@
 
 package v1
 
+type KerbernetesClusterExpansion interface{}
+
 type LdapGroupBindingExpansion interface{}
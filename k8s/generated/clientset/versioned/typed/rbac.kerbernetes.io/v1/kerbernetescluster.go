@@ -0,0 +1,60 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	context "context"
+
+	rbackerbernetesiov1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	applyconfigurationrbackerbernetesiov1 "github.com/froz42/kerbernetes/k8s/generated/applyconfiguration/rbac.kerbernetes.io/v1"
+	scheme "github.com/froz42/kerbernetes/k8s/generated/clientset/versioned/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// KerbernetesClustersGetter has a method to return a KerbernetesClusterInterface.
+// A group's client should implement this interface.
+type KerbernetesClustersGetter interface {
+	KerbernetesClusters() KerbernetesClusterInterface
+}
+
+// KerbernetesClusterInterface has methods to work with KerbernetesCluster resources.
+type KerbernetesClusterInterface interface {
+	Create(ctx context.Context, kerbernetesCluster *rbackerbernetesiov1.KerbernetesCluster, opts metav1.CreateOptions) (*rbackerbernetesiov1.KerbernetesCluster, error)
+	Update(ctx context.Context, kerbernetesCluster *rbackerbernetesiov1.KerbernetesCluster, opts metav1.UpdateOptions) (*rbackerbernetesiov1.KerbernetesCluster, error)
+	// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+	UpdateStatus(ctx context.Context, kerbernetesCluster *rbackerbernetesiov1.KerbernetesCluster, opts metav1.UpdateOptions) (*rbackerbernetesiov1.KerbernetesCluster, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*rbackerbernetesiov1.KerbernetesCluster, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*rbackerbernetesiov1.KerbernetesClusterList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *rbackerbernetesiov1.KerbernetesCluster, err error)
+	Apply(ctx context.Context, kerbernetesCluster *applyconfigurationrbackerbernetesiov1.KerbernetesClusterApplyConfiguration, opts metav1.ApplyOptions) (result *rbackerbernetesiov1.KerbernetesCluster, err error)
+	// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+	ApplyStatus(ctx context.Context, kerbernetesCluster *applyconfigurationrbackerbernetesiov1.KerbernetesClusterApplyConfiguration, opts metav1.ApplyOptions) (result *rbackerbernetesiov1.KerbernetesCluster, err error)
+	KerbernetesClusterExpansion
+}
+
+// kerbernetesClusters implements KerbernetesClusterInterface
+type kerbernetesClusters struct {
+	*gentype.ClientWithListAndApply[*rbackerbernetesiov1.KerbernetesCluster, *rbackerbernetesiov1.KerbernetesClusterList, *applyconfigurationrbackerbernetesiov1.KerbernetesClusterApplyConfiguration]
+}
+
+// newKerbernetesClusters returns a KerbernetesClusters
+func newKerbernetesClusters(c *RbacKerbernetesV1Client) *kerbernetesClusters {
+	return &kerbernetesClusters{
+		gentype.NewClientWithListAndApply[*rbackerbernetesiov1.KerbernetesCluster, *rbackerbernetesiov1.KerbernetesClusterList, *applyconfigurationrbackerbernetesiov1.KerbernetesClusterApplyConfiguration](
+			"kerbernetesclusters",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			"",
+			func() *rbackerbernetesiov1.KerbernetesCluster { return &rbackerbernetesiov1.KerbernetesCluster{} },
+			func() *rbackerbernetesiov1.KerbernetesClusterList {
+				return &rbackerbernetesiov1.KerbernetesClusterList{}
+			},
+		),
+	}
+}
@@ -24,6 +24,8 @@ type LdapGroupBindingsGetter interface {
 type LdapGroupBindingInterface interface {
 	Create(ctx context.Context, ldapGroupBinding *rbackerbernetesiov1.LdapGroupBinding, opts metav1.CreateOptions) (*rbackerbernetesiov1.LdapGroupBinding, error)
 	Update(ctx context.Context, ldapGroupBinding *rbackerbernetesiov1.LdapGroupBinding, opts metav1.UpdateOptions) (*rbackerbernetesiov1.LdapGroupBinding, error)
+	// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+	UpdateStatus(ctx context.Context, ldapGroupBinding *rbackerbernetesiov1.LdapGroupBinding, opts metav1.UpdateOptions) (*rbackerbernetesiov1.LdapGroupBinding, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*rbackerbernetesiov1.LdapGroupBinding, error)
@@ -31,6 +33,8 @@ type LdapGroupBindingInterface interface {
 	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
 	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *rbackerbernetesiov1.LdapGroupBinding, err error)
 	Apply(ctx context.Context, ldapGroupBinding *applyconfigurationrbackerbernetesiov1.LdapGroupBindingApplyConfiguration, opts metav1.ApplyOptions) (result *rbackerbernetesiov1.LdapGroupBinding, err error)
+	// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+	ApplyStatus(ctx context.Context, ldapGroupBinding *applyconfigurationrbackerbernetesiov1.LdapGroupBindingApplyConfiguration, opts metav1.ApplyOptions) (result *rbackerbernetesiov1.LdapGroupBinding, err error)
 	LdapGroupBindingExpansion
 }
 
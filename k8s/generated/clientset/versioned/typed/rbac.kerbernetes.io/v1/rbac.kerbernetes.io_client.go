@@ -12,6 +12,7 @@ import (
 
 type RbacKerbernetesV1Interface interface {
 	RESTClient() rest.Interface
+	KerbernetesClustersGetter
 	LdapGroupBindingsGetter
 }
 
@@ -20,6 +21,10 @@ type RbacKerbernetesV1Client struct {
 	restClient rest.Interface
 }
 
+func (c *RbacKerbernetesV1Client) KerbernetesClusters() KerbernetesClusterInterface {
+	return newKerbernetesClusters(c)
+}
+
 func (c *RbacKerbernetesV1Client) LdapGroupBindings() LdapGroupBindingInterface {
 	return newLdapGroupBindings(c)
 }
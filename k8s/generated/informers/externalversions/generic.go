@@ -37,6 +37,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=rbac.kerbernetes.io, Version=v1
+	case v1.SchemeGroupVersion.WithResource("kerbernetesclusters"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.RbacKerbernetes().V1().KerbernetesClusters().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("ldapgroupbindings"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.RbacKerbernetes().V1().LdapGroupBindings().Informer()}, nil
 
@@ -8,6 +8,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// KerbernetesClusters returns a KerbernetesClusterInformer.
+	KerbernetesClusters() KerbernetesClusterInformer
 	// LdapGroupBindings returns a LdapGroupBindingInformer.
 	LdapGroupBindings() LdapGroupBindingInformer
 }
@@ -23,6 +25,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// KerbernetesClusters returns a KerbernetesClusterInformer.
+func (v *version) KerbernetesClusters() KerbernetesClusterInformer {
+	return &kerbernetesClusterInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // LdapGroupBindings returns a LdapGroupBindingInformer.
 func (v *version) LdapGroupBindings() LdapGroupBindingInformer {
 	return &ldapGroupBindingInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
@@ -0,0 +1,85 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	context "context"
+	time "time"
+
+	apirbackerbernetesiov1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	versioned "github.com/froz42/kerbernetes/k8s/generated/clientset/versioned"
+	internalinterfaces "github.com/froz42/kerbernetes/k8s/generated/informers/externalversions/internalinterfaces"
+	rbackerbernetesiov1 "github.com/froz42/kerbernetes/k8s/generated/listers/rbac.kerbernetes.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// KerbernetesClusterInformer provides access to a shared informer and lister for
+// KerbernetesClusters.
+type KerbernetesClusterInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() rbackerbernetesiov1.KerbernetesClusterLister
+}
+
+type kerbernetesClusterInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewKerbernetesClusterInformer constructs a new informer for KerbernetesCluster type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewKerbernetesClusterInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredKerbernetesClusterInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredKerbernetesClusterInformer constructs a new informer for KerbernetesCluster type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredKerbernetesClusterInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.RbacKerbernetesV1().KerbernetesClusters().List(context.Background(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.RbacKerbernetesV1().KerbernetesClusters().Watch(context.Background(), options)
+			},
+			ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.RbacKerbernetesV1().KerbernetesClusters().List(ctx, options)
+			},
+			WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.RbacKerbernetesV1().KerbernetesClusters().Watch(ctx, options)
+			},
+		},
+		&apirbackerbernetesiov1.KerbernetesCluster{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *kerbernetesClusterInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredKerbernetesClusterInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *kerbernetesClusterInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&apirbackerbernetesiov1.KerbernetesCluster{}, f.defaultInformer)
+}
+
+func (f *kerbernetesClusterInformer) Lister() rbackerbernetesiov1.KerbernetesClusterLister {
+	return rbackerbernetesiov1.NewKerbernetesClusterLister(f.Informer().GetIndexer())
+}
@@ -2,6 +2,10 @@
 
 package v1
 
+// KerbernetesClusterListerExpansion allows custom methods to be added to
+// KerbernetesClusterLister.
+type KerbernetesClusterListerExpansion interface{}
+
 // LdapGroupBindingListerExpansion allows custom methods to be added to
 // LdapGroupBindingLister.
 type LdapGroupBindingListerExpansion interface{}
@@ -0,0 +1,32 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	rbackerbernetesiov1 "github.com/froz42/kerbernetes/k8s/api/rbac.kerbernetes.io/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// KerbernetesClusterLister helps list KerbernetesClusters.
+// All objects returned here must be treated as read-only.
+type KerbernetesClusterLister interface {
+	// List lists all KerbernetesClusters in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*rbackerbernetesiov1.KerbernetesCluster, err error)
+	// Get retrieves the KerbernetesCluster from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*rbackerbernetesiov1.KerbernetesCluster, error)
+	KerbernetesClusterListerExpansion
+}
+
+// kerbernetesClusterLister implements the KerbernetesClusterLister interface.
+type kerbernetesClusterLister struct {
+	listers.ResourceIndexer[*rbackerbernetesiov1.KerbernetesCluster]
+}
+
+// NewKerbernetesClusterLister returns a new KerbernetesClusterLister.
+func NewKerbernetesClusterLister(indexer cache.Indexer) KerbernetesClusterLister {
+	return &kerbernetesClusterLister{listers.New[*rbackerbernetesiov1.KerbernetesCluster](indexer, rbackerbernetesiov1.Resource("kerbernetescluster"))}
+}